@@ -0,0 +1,287 @@
+package hrr
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// Ein gespeicherter API Key. Gespeichert wird nur der Hash des geheimen Teils,
+	// der Prefix dient zum Nachschlagen.
+	APIKey struct {
+		Prefix    string    `db:"prefix"`
+		Hash      string    `db:"hash"`
+		Owner     string    `db:"owner"`
+		Scopes    []string  `db:"-"`
+		ExpiresAt time.Time `db:"-"`
+	}
+
+	// Speicher für API Keys
+	KeyStore interface {
+		// Liefert den Key zum Prefix, nil wenn er nicht existiert
+		Lookup(prefix string) (*APIKey, error)
+	}
+
+	// Wo der API Key im Request steht, leere Felder werden nicht geprüft
+	APIKeyConfig struct {
+		Header string
+		Query  string
+		Cookie string
+		Store  KeyStore
+	}
+
+	// API Keys im Speicher
+	MemoryKeyStore struct {
+		mu   sync.RWMutex
+		keys map[string]APIKey
+	}
+
+	// API Keys in einer SQL Tabelle, siehe SQLKeyStoreSchema
+	SQLKeyStore struct {
+		db    *sqlx.DB
+		table string
+	}
+
+	sqlAPIKey struct {
+		APIKey
+		ScopeList string       `db:"scopes"`
+		Expires   sql.NullTime `db:"expires_at"`
+	}
+)
+
+// Tabelle für SQLKeyStore, %v wird durch den Tabellennamen ersetzt
+const SQLKeyStoreSchema = `CREATE TABLE IF NOT EXISTS %v (
+	prefix     TEXT PRIMARY KEY,
+	hash       TEXT NOT NULL,
+	owner      TEXT NOT NULL,
+	scopes     TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NULL
+)`
+
+var (
+	errMissingAPIKey = errors.New("Missing API key")
+	errInvalidAPIKey = errors.New("Invalid API key")
+	errExpiredAPIKey = errors.New("Expired API key")
+)
+
+// Aktiviere API Key Auth. Ein Key hat die Form prefix.secret, der Aufrufer
+// erhält die Scopes des Keys und ist über Principal erreichbar.
+// Steht der Key in der Query wird er aus der URL des Requests entfernt,
+// damit er nicht mit der URL geloggt wird.
+func (r *request) APIKeyAuth(config APIKeyConfig) *request {
+	var queryKey string
+	if config.Query != "" {
+		queryKey = r.takeQueryParam(config.Query)
+	}
+
+	r.authenticate = func() (*Identity, Error) {
+		key, ok := r.apiKey(config, queryKey)
+		if !ok {
+			return nil, Unauthorized("Unauthorized API key", errMissingAPIKey)
+		}
+
+		id, err := verifyAPIKey(config.Store, key)
+		if err != nil {
+			if err == errInvalidAPIKey || err == errExpiredAPIKey {
+				return nil, Unauthorized("Unauthorized API key", err)
+			}
+
+			return nil, Internal("Error cannot verify API key", err)
+		}
+
+		return id, nil
+	}
+
+	return r
+}
+
+// Erzeuge neuen API Key. key wird dem Client übergeben, record gespeichert.
+func GenerateAPIKey(owner string, scopes []string, expiresAt time.Time) (string, APIKey, error) {
+	p := make([]byte, 6)
+	s := make([]byte, 32)
+	if _, err := rand.Read(p); err != nil {
+		return "", APIKey{}, err
+	}
+	if _, err := rand.Read(s); err != nil {
+		return "", APIKey{}, err
+	}
+
+	prefix := hex.EncodeToString(p)
+	secret := base64.RawURLEncoding.EncodeToString(s)
+
+	record := APIKey{
+		Prefix:    prefix,
+		Hash:      HashAPIKeySecret(secret),
+		Owner:     owner,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	return prefix + "." + secret, record, nil
+}
+
+// Hash des geheimen Teils eines API Keys
+func HashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *request) apiKey(config APIKeyConfig, queryKey string) (string, bool) {
+	if config.Header != "" {
+		if v := r.request.Header.Get(config.Header); v != "" {
+			return v, true
+		}
+	}
+
+	if queryKey != "" {
+		return queryKey, true
+	}
+
+	if config.Cookie != "" {
+		if c, err := r.request.Cookie(config.Cookie); err == nil && c.Value != "" {
+			return c.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Liest einen Query Parameter und entfernt ihn aus URL und RequestURI
+func (r *request) takeQueryParam(name string) string {
+	query := r.request.URL.Query()
+	if _, ok := query[name]; !ok {
+		return ""
+	}
+
+	value := query.Get(name)
+	query.Del(name)
+	r.request.URL.RawQuery = query.Encode()
+	if r.request.RequestURI != "" {
+		r.request.RequestURI = r.request.URL.RequestURI()
+	}
+
+	return value
+}
+
+func verifyAPIKey(store KeyStore, key string) (*Identity, error) {
+	i := strings.Index(key, ".")
+	if i <= 0 {
+		return nil, errInvalidAPIKey
+	}
+
+	record, err := store.Lookup(key[:i])
+	if err != nil {
+		return nil, err
+	}
+
+	if record == nil {
+		return nil, errInvalidAPIKey
+	}
+
+	hash := HashAPIKeySecret(key[i+1:])
+	if subtle.ConstantTimeCompare([]byte(hash), []byte(record.Hash)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+
+	if !record.ExpiresAt.IsZero() && time.Now().After(record.ExpiresAt) {
+		return nil, errExpiredAPIKey
+	}
+
+	return &Identity{
+		ID:     record.Owner,
+		Scopes: record.Scopes,
+		Claims: map[string]interface{}{"api_key": record.Prefix},
+	}, nil
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: map[string]APIKey{}}
+}
+
+func (s *MemoryKeyStore) Add(key APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[key.Prefix] = key
+}
+
+func (s *MemoryKeyStore) Remove(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, prefix)
+}
+
+func (s *MemoryKeyStore) Lookup(prefix string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[prefix]
+	if !ok {
+		return nil, nil
+	}
+
+	return &key, nil
+}
+
+// Erzeuge SQLKeyStore und lege die Tabelle an falls sie fehlt
+func NewSQLKeyStore(db *sqlx.DB, table string) (*SQLKeyStore, error) {
+	_, err := db.Exec(fmt.Sprintf(SQLKeyStoreSchema, table))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLKeyStore{db: db, table: table}, nil
+}
+
+func (s *SQLKeyStore) Add(key APIKey) error {
+	record := sqlAPIKey{
+		APIKey:    key,
+		ScopeList: strings.Join(key.Scopes, " "),
+		Expires:   sql.NullTime{Time: key.ExpiresAt, Valid: !key.ExpiresAt.IsZero()},
+	}
+
+	q := fmt.Sprintf(`INSERT INTO %v (prefix, hash, owner, scopes, expires_at)
+		VALUES (:prefix, :hash, :owner, :scopes, :expires_at)`, s.table)
+	_, err := s.db.NamedExec(q, record)
+
+	return err
+}
+
+func (s *SQLKeyStore) Remove(prefix string) error {
+	q := s.db.Rebind(fmt.Sprintf(`DELETE FROM %v WHERE prefix = ?`, s.table))
+	_, err := s.db.Exec(q, prefix)
+
+	return err
+}
+
+func (s *SQLKeyStore) Lookup(prefix string) (*APIKey, error) {
+	var record sqlAPIKey
+	q := s.db.Rebind(fmt.Sprintf(`SELECT prefix, hash, owner, scopes, expires_at FROM %v WHERE prefix = ?`, s.table))
+	err := s.db.Get(&record, q, prefix)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := record.APIKey
+	key.Scopes = strings.Fields(record.ScopeList)
+	if record.Expires.Valid {
+		key.ExpiresAt = record.Expires.Time
+	}
+
+	return &key, nil
+}
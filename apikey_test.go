@@ -0,0 +1,172 @@
+package hrr
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func Test_APIKeyAuth(t *testing.T) {
+	store := NewMemoryKeyStore()
+
+	valid, record, err := GenerateAPIKey("service-a", []string{"monsters:read"}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(record)
+
+	expired, record, err := GenerateAPIKey("service-b", nil, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(record)
+
+	config := APIKeyConfig{
+		Header: "X-API-Key",
+		Query:  "api_key",
+		Cookie: "api_key",
+		Store:  store,
+	}
+
+	tc := []struct {
+		Header         string
+		Query          string
+		Cookie         string
+		ExpectedStatus int
+	}{
+		{Header: valid},
+		{Query: valid},
+		{Cookie: valid},
+		{ExpectedStatus: http.StatusUnauthorized},
+		{Header: expired, ExpectedStatus: http.StatusUnauthorized},
+		{Header: valid + "x", ExpectedStatus: http.StatusUnauthorized},
+		{Header: "unknown.secret", ExpectedStatus: http.StatusUnauthorized},
+		{Header: "nodot", ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/?api_key="+v.Query, &bytes.Buffer{})
+		if v.Header != "" {
+			req.Header.Set("X-API-Key", v.Header)
+		}
+		if v.Cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "api_key", Value: v.Cookie})
+		}
+
+		err := Request(req).APIKeyAuth(config).Require(Scopes("monsters:read")).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+
+			if id := Principal(req); id == nil || id.ID != "service-a" {
+				t.Fatalf("%v: Expected service-a was %v", i, id)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+}
+
+func Test_APIKeyAuthQueryNotLogged(t *testing.T) {
+	store := NewMemoryKeyStore()
+	key, record, err := GenerateAPIKey("service-a", nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(record)
+
+	// Run test
+	{
+		logger, mock := test.NewNullLogger()
+		e := NewEngine()
+		e.Logger = logger
+		e.LogAllRequests = true
+
+		req := NewRequest(t, "POST", "/v0/monster?api_key="+key+"&dry_run=1", bytes.NewBufferString(`{}`))
+
+		var body TestObject
+		err := e.Request(req).APIKeyAuth(APIKeyConfig{Query: "api_key", Store: store}).Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		e.Response(httptest.NewRecorder(), req).Error(err)
+
+		if len(mock.Entries) != 2 {
+			t.Fatalf("Expected request and error entry was %v", mock.Entries)
+		}
+
+		for _, entry := range mock.Entries {
+			u := fmt.Sprint(entry.Data["url"])
+			if strings.Contains(u, key[strings.Index(key, ".")+1:]) || u != "/v0/monster?dry_run=1" {
+				t.Fatalf("Expected url without API key was %v", u)
+			}
+		}
+	}
+}
+
+func Test_SQLKeyStore(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLKeyStore(db, "api_keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	key, record, err := GenerateAPIKey("service-a", []string{"a", "b"}, expires)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run test
+	{
+		if err := store.Add(record); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := store.Lookup(record.Prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result == nil ||
+			result.Hash != record.Hash ||
+			result.Owner != "service-a" ||
+			len(result.Scopes) != 2 ||
+			!result.ExpiresAt.Equal(expires) {
+			t.Fatalf("Expected %v was %v", record, result)
+		}
+
+		id, err := verifyAPIKey(store, key)
+		if err != nil || id.ID != "service-a" {
+			t.Fatalf("Expected service-a was (%v, %v)", id, err)
+		}
+
+		if err := store.Remove(record.Prefix); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err = store.Lookup(record.Prefix)
+		if err != nil || result != nil {
+			t.Fatalf("Expected nil was (%v, %v)", result, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package hrr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type (
+	// Prüft einen Bearer Token
+	bearerFunc func(token string) (bool, error)
+)
+
+var errMissingBearer = errors.New("Missing bearer token")
+
+// Aktiviere Bearer Token Auth (RFC 6750)
+func (r *request) BearerAuth(fn bearerFunc) *request {
+	return r.BearerAuthIdentity(func(token string) (*Identity, error) {
+		ok, err := fn(token)
+		if !ok || err != nil {
+			return nil, err
+		}
+
+		return anonymous, nil
+	})
+}
+
+func (r *request) bearerAuth(fn func(string) (*Identity, error)) func() (*Identity, Error) {
+	return func() (*Identity, Error) {
+		token, ok := bearerToken(r.request.Header.Get("Authorization"))
+		if !ok {
+			return nil, r.bearerError("", errMissingBearer)
+		}
+
+		id, err := fn(token)
+		if id == nil || err != nil {
+			if err == nil {
+				err = errors.New("Invalid bearer token")
+			}
+			return nil, r.bearerError("invalid_token", err)
+		}
+
+		return id, nil
+	}
+}
+
+func (r *request) bearerError(code string, err error) Error {
+	challenge := fmt.Sprintf("Bearer realm=%q", r.engine.authRealm())
+	if code != "" {
+		challenge += fmt.Sprintf(", error=%q", code)
+	}
+
+	return Unauthorized("Unauthorized token", err).
+		WithHeader("WWW-Authenticate", challenge)
+}
+
+// Liest den Token aus einem Authorization Header
+func bearerToken(header string) (string, bool) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[len(prefix):])
+
+	return token, token != ""
+}
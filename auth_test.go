@@ -0,0 +1,60 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func Test_BearerAuth(t *testing.T) {
+	tc := []struct {
+		Authorization  string
+		ExpectedError  bool
+		ExpectedHeader string
+	}{
+		{
+			Authorization: "Bearer secret",
+			ExpectedError: false,
+		},
+		{
+			Authorization: "bearer secret",
+			ExpectedError: false,
+		},
+		{
+			Authorization:  "",
+			ExpectedError:  true,
+			ExpectedHeader: `Bearer realm="Restricted"`,
+		},
+		{
+			Authorization:  "Bearer wrong",
+			ExpectedError:  true,
+			ExpectedHeader: `Bearer realm="Restricted", error="invalid_token"`,
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("Authorization", v.Authorization)
+
+		err := Request(req).BearerAuth(func(token string) (bool, error) {
+			return token == "secret", nil
+		}).Process()
+
+		if !v.ExpectedError {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		s, ok := err.(StatusError)
+		if !ok || s.Status() != http.StatusUnauthorized {
+			t.Fatalf("Expected %v was %v", http.StatusUnauthorized, err)
+		}
+
+		if h := err.(HeaderError).Header().Get("WWW-Authenticate"); h != v.ExpectedHeader {
+			t.Fatalf("Expected %v was %v", v.ExpectedHeader, h)
+		}
+	}
+}
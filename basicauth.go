@@ -0,0 +1,377 @@
+package hrr
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type (
+	// Liefert den Passwort Hash eines Benutzers
+	PasswordStore interface {
+		Password(user string) (hash string, ok bool, err error)
+	}
+
+	// Passwort Hashes nach Benutzer wie in einer htpasswd Datei.
+	// Unterstützt werden bcrypt ($2a$, $2b$, $2y$) und argon2id Hashes.
+	Htpasswd map[string]string
+
+	// Prüft Benutzer und Passwort bei Basic Auth, optional mit Sperre nach Fehlversuchen
+	BasicVerifier struct {
+		Users   PasswordStore
+		Lockout *Lockout
+	}
+
+	// Sperrt Benutzer und IP Adressen nach MaxAttempts Fehlversuchen.
+	// Die Sperre beginnt mit BaseDelay und verdoppelt sich mit jedem weiteren
+	// Fehlversuch bis MaxDelay, 0 bedeutet keine Obergrenze.
+	Lockout struct {
+		MaxAttempts int
+		BaseDelay   time.Duration
+		MaxDelay    time.Duration
+
+		mu        sync.Mutex
+		entries   map[string]*lockoutEntry
+		lastSweep time.Time
+	}
+
+	lockoutEntry struct {
+		failures int
+		until    time.Time
+		last     time.Time
+	}
+)
+
+var (
+	errUnknownUser     = errors.New("Unknown user")
+	errWrongPassword   = errors.New("Wrong password")
+	errLockedOut       = errors.New("Too many failed attempts")
+	errUnsupportedHash = errors.New("Unsupported password hash")
+
+	// Hash für unbekannte Benutzer, damit die Antwortzeit gleich bleibt
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// Lese eine htpasswd Datei mit Zeilen der Form user:hash
+func LoadHtpasswd(path string) (Htpasswd, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := Htpasswd{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.Index(line, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid htpasswd line %v", n)
+		}
+
+		users[line[:i]] = line[i+1:]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (h Htpasswd) Password(user string) (string, bool, error) {
+	hash, ok := h[user]
+	return hash, ok, nil
+}
+
+// Erzeuge einen bcrypt Hash für Htpasswd
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// Vergleiche ein Passwort mit einem bcrypt oder argon2id Hash
+func CheckPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, nil
+		}
+
+		return err == nil, err
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return checkArgon2id(hash, password)
+	}
+
+	return false, errUnsupportedHash
+}
+
+// Format: $argon2id$v=19$m=65536,t=3,p=2$salt$hash
+func checkArgon2id(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[2] != "v=19" {
+		return false, errUnsupportedHash
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads)
+	if err != nil {
+		return false, errUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, errUnsupportedHash
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, errUnsupportedHash
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(expected, actual) == 1, nil
+}
+
+func NewBasicVerifier(users PasswordStore, lockout *Lockout) *BasicVerifier {
+	return &BasicVerifier{Users: users, Lockout: lockout}
+}
+
+// Aktiviere Base Auth mit dem übergebenen Verifier. Fehlversuche werden unabhängig
+// von Log in den Logger geschrieben. Gesperrte Aufrufer erhalten 429 Too Many Requests.
+// Die IP Adresse stammt aus RemoteAddr, Proxy Header werden nicht beachtet.
+func (r *request) VerifyBasicAuth(v *BasicVerifier) *request {
+	r.authenticate = func() (*Identity, Error) {
+		user, password, _ := r.request.BasicAuth()
+		ip := remoteIP(r.request.RemoteAddr)
+		keys := []string{"ip:" + ip}
+		if user != "" {
+			keys = append(keys, "user:"+user)
+		}
+
+		if v.Lockout != nil {
+			if wait := v.Lockout.wait(keys...); wait > 0 {
+				r.auditAuthFailure(user, ip, errLockedOut)
+				retry := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+				return nil, TooManyRequests("Too many failed login attempts", errLockedOut).
+					WithHeader("Retry-After", retry)
+			}
+		}
+
+		err := v.verify(user, password)
+		if err == errUnknownUser || err == errWrongPassword {
+			r.auditAuthFailure(user, ip, err)
+			if v.Lockout != nil {
+				// Nur vorhandene Benutzer zählen, sonst wächst die Sperrliste
+				// mit jedem ausgedachten Benutzernamen
+				if err == errUnknownUser {
+					keys = keys[:1]
+				}
+
+				v.Lockout.fail(keys...)
+			}
+
+			challenge := fmt.Sprintf("Basic realm=%q", r.engine.authRealm())
+			return nil, Unauthorized("Unauthorized user", err).
+				WithHeader("WWW-Authenticate", challenge)
+		}
+
+		if err != nil {
+			return nil, Internal("Error cannot verify user", err)
+		}
+
+		// Die IP bleibt gesperrt, sonst könnte ein gültiger Zugang die Sperre aufheben
+		if v.Lockout != nil {
+			v.Lockout.reset("user:" + user)
+		}
+
+		return &Identity{ID: user}, nil
+	}
+
+	return r
+}
+
+func (v *BasicVerifier) verify(user, password string) error {
+	hash, ok, err := v.Users.Password(user)
+	if err != nil {
+		return err
+	}
+
+	if !ok || user == "" {
+		// Gleicher Aufwand wie bei bekannten Benutzern
+		bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+		return errUnknownUser
+	}
+
+	match, err := CheckPassword(hash, password)
+	if err != nil {
+		return err
+	}
+
+	if !match {
+		return errWrongPassword
+	}
+
+	return nil
+}
+
+func unknownUserHash() []byte {
+	dummyHashOnce.Do(func() {
+		secret := make([]byte, 16)
+		rand.Read(secret)
+		dummyHash, _ = bcrypt.GenerateFromPassword(secret, bcrypt.DefaultCost)
+	})
+
+	return dummyHash
+}
+
+// Schreibe Fehlversuch ins Audit Log, der Benutzername wird gekürzt und maskiert
+func (r *request) auditAuthFailure(user, ip string, reason error) {
+	if len(user) > 64 {
+		user = user[:64]
+	}
+
+	r.engine.logger().WithFields(logrus.Fields{
+		"event":       "auth_failure",
+		"reason":      reason.Error(),
+		"user":        strconv.Quote(user),
+		"remote_addr": ip,
+		"url":         r.request.URL.Path,
+	}).Warnln("Basic auth failed")
+}
+
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+func NewLockout(maxAttempts int, baseDelay, maxDelay time.Duration) *Lockout {
+	return &Lockout{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   baseDelay,
+		MaxDelay:    maxDelay,
+	}
+}
+
+// Längste verbleibende Sperre der übergebenen Schlüssel
+func (l *Lockout) wait(keys ...string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var wait time.Duration
+	for _, k := range keys {
+		e, ok := l.entries[k]
+		if !ok {
+			continue
+		}
+
+		if d := e.until.Sub(now); d > wait {
+			wait = d
+		}
+	}
+
+	return wait
+}
+
+func (l *Lockout) fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.entries == nil {
+		l.entries = map[string]*lockoutEntry{}
+	}
+	l.sweep(now)
+
+	for _, k := range keys {
+		e, ok := l.entries[k]
+		if !ok {
+			e = &lockoutEntry{}
+			l.entries[k] = e
+		}
+
+		e.failures++
+		e.last = now
+		if e.failures >= l.MaxAttempts {
+			e.until = now.Add(l.delay(e.failures - l.MaxAttempts))
+		}
+	}
+}
+
+func (l *Lockout) reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, k := range keys {
+		delete(l.entries, k)
+	}
+}
+
+func (l *Lockout) delay(n int) time.Duration {
+	d := l.BaseDelay
+	for i := 0; i < n && (l.MaxDelay == 0 || d < l.MaxDelay) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+
+	if l.MaxDelay > 0 && d > l.MaxDelay {
+		return l.MaxDelay
+	}
+
+	return d
+}
+
+// Entferne Einträge deren letzter Fehlversuch länger als MaxDelay zurückliegt.
+// Ohne MaxDelay werden Einträge nach der Dauer der nächsten Sperre vergessen.
+func (l *Lockout) sweep(now time.Time) {
+	interval := l.MaxDelay
+	if interval == 0 {
+		interval = time.Minute
+	}
+
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+	l.lastSweep = now
+
+	for k, e := range l.entries {
+		retention := l.MaxDelay
+		if retention == 0 {
+			retention = l.delay(e.failures - l.MaxAttempts + 1)
+		}
+
+		if now.Sub(e.last) > retention && now.After(e.until) {
+			delete(l.entries, k)
+		}
+	}
+}
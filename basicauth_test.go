@@ -0,0 +1,211 @@
+package hrr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func Test_CheckPassword(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("gruffalo"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("gruffalo"), salt, 1, 1024, 1, 32)
+	argonHash := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%v$%v",
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key))
+
+	tc := []struct {
+		Hash          string
+		Password      string
+		Expected      bool
+		ExpectedError bool
+	}{
+		{Hash: string(bcryptHash), Password: "gruffalo", Expected: true},
+		{Hash: string(bcryptHash), Password: "mouse"},
+		{Hash: argonHash, Password: "gruffalo", Expected: true},
+		{Hash: argonHash, Password: "mouse"},
+		{Hash: "{SHA}abc", Password: "gruffalo", ExpectedError: true},
+		{Hash: "$argon2id$v=19$broken", Password: "gruffalo", ExpectedError: true},
+	}
+
+	// Run test
+	for i, v := range tc {
+		ok, err := CheckPassword(v.Hash, v.Password)
+		if (err != nil) != v.ExpectedError {
+			t.Fatalf("%v: Expected error %v was %v", i, v.ExpectedError, err)
+		}
+
+		if ok != v.Expected {
+			t.Fatalf("%v: Expected %v was %v", i, v.Expected, ok)
+		}
+	}
+}
+
+func Test_LoadHtpasswd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "htpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".htpasswd")
+	data := "# Monster\nkrake:$2y$05$hash\n\nyeti:$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Run test
+	{
+		users, err := LoadHtpasswd(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 2 || users["krake"] != "$2y$05$hash" {
+			t.Fatalf("Expected two users was %v", users)
+		}
+
+		if err := ioutil.WriteFile(path, []byte("broken\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadHtpasswd(path); err == nil {
+			t.Fatal("Expected error for invalid line")
+		}
+	}
+}
+
+func Test_VerifyBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("gruffalo"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger, hook := test.NewNullLogger()
+	Logger = logger
+
+	verifier := NewBasicVerifier(
+		Htpasswd{"krake": string(hash)},
+		NewLockout(2, time.Minute, time.Hour),
+	)
+
+	tc := []struct {
+		User           string
+		Password       string
+		RemoteAddr     string
+		ExpectedStatus int
+	}{
+		{User: "krake", Password: "gruffalo", RemoteAddr: "10.0.0.1:1234"},
+		{User: "krake", Password: "mouse", RemoteAddr: "10.0.0.1:1234", ExpectedStatus: http.StatusUnauthorized},
+		// Erfolg setzt den Zähler des Benutzers zurück
+		{User: "krake", Password: "gruffalo", RemoteAddr: "10.0.0.2:1234"},
+		{User: "krake", Password: "mouse", RemoteAddr: "10.0.0.2:1234", ExpectedStatus: http.StatusUnauthorized},
+		{User: "krake", Password: "mouse", RemoteAddr: "10.0.0.3:1234", ExpectedStatus: http.StatusUnauthorized},
+		// Benutzer ist gesperrt, auch mit richtigem Passwort
+		{User: "krake", Password: "gruffalo", RemoteAddr: "10.0.0.4:1234", ExpectedStatus: http.StatusTooManyRequests},
+		{User: "nobody\n", Password: "x", RemoteAddr: "10.0.0.5:1234", ExpectedStatus: http.StatusUnauthorized},
+		{User: "other", Password: "x", RemoteAddr: "10.0.0.5:1234", ExpectedStatus: http.StatusUnauthorized},
+		// IP ist gesperrt
+		{User: "krake", Password: "gruffalo", RemoteAddr: "10.0.0.5:1234", ExpectedStatus: http.StatusTooManyRequests},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth(v.User, v.Password)
+		req.RemoteAddr = v.RemoteAddr
+
+		err := Request(req).VerifyBasicAuth(verifier).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+
+			if id := Principal(req); id == nil || id.ID != v.User {
+				t.Fatalf("%v: Expected %v was %v", i, v.User, id)
+			}
+			continue
+		}
+
+		s, ok := err.(StatusError)
+		if !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+
+		if v.ExpectedStatus == http.StatusTooManyRequests && err.(HeaderError).Header().Get("Retry-After") == "" {
+			t.Fatalf("%v: Expected Retry-After header", i)
+		}
+
+		entry := hook.LastEntry()
+		if entry == nil || entry.Data["event"] != "auth_failure" || entry.Data["remote_addr"] != v.RemoteAddr[:8] {
+			t.Fatalf("%v: Expected audit entry was %v", i, entry)
+		}
+
+		if entry.Data["user"] != fmt.Sprintf("%q", v.User) {
+			t.Fatalf("%v: Expected quoted user was %v", i, entry.Data["user"])
+		}
+	}
+}
+
+func Test_LockoutDelay(t *testing.T) {
+	tc := []struct {
+		Lockout  *Lockout
+		Failures int
+		Expected time.Duration
+	}{
+		{Lockout: NewLockout(3, time.Second, time.Minute), Failures: 0, Expected: time.Second},
+		{Lockout: NewLockout(3, time.Second, time.Minute), Failures: 3, Expected: 8 * time.Second},
+		{Lockout: NewLockout(3, time.Second, time.Minute), Failures: 10, Expected: time.Minute},
+		// Ohne MaxDelay keine Obergrenze
+		{Lockout: NewLockout(3, time.Second, 0), Failures: 10, Expected: 1024 * time.Second},
+		{Lockout: NewLockout(3, time.Second, 0), Failures: 1000, Expected: time.Second << 33},
+	}
+
+	// Run test
+	for i, v := range tc {
+		if d := v.Lockout.delay(v.Failures); d != v.Expected {
+			t.Fatalf("%v: Expected %v was %v", i, v.Expected, d)
+		}
+	}
+}
+
+func Test_LockoutUnknownUsers(t *testing.T) {
+	lockout := NewLockout(2, time.Minute, 0)
+	verifier := NewBasicVerifier(Htpasswd{}, lockout)
+
+	logger, _ := test.NewNullLogger()
+	Logger = logger
+
+	// Run test
+	{
+		for i := 0; i < 100; i++ {
+			req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+			req.SetBasicAuth(fmt.Sprintf("user%v", i), "x")
+			req.RemoteAddr = "10.0.0.1:1234"
+
+			Request(req).VerifyBasicAuth(verifier).Process()
+		}
+
+		if n := len(lockout.entries); n != 1 {
+			t.Fatalf("Expected only the IP entry was %v", lockout.entries)
+		}
+
+		if e := lockout.entries["ip:10.0.0.1"]; e == nil || e.until.IsZero() {
+			t.Fatalf("Expected locked IP was %v", e)
+		}
+	}
+}
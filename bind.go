@@ -0,0 +1,237 @@
+package hrr
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Bindet Werte aus dem Request (Query String, Header, Cookies) an die Felder eines Structs.
+	// Welche Felder gebunden werden legt der Struct Tag fest, z.B.
+	// `query:"sorted_by,required" default:"name"`.
+	binding struct {
+		dst    interface{}
+		tag    string
+		kind   string
+		lookup func(name string) []string
+	}
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Binde Query Parameter an die mit `query:"..."` markierten Felder von dst.
+// Mehrfach übergebene Parameter werden in Slices abgelegt, mit `default:"..."` kann
+// ein Wert für fehlende Parameter gesetzt werden. Anschließend wird dst validiert.
+func (r *request) Query(dst interface{}) *request {
+	r.bindings = append(r.bindings, &binding{
+		dst:  dst,
+		tag:  "query",
+		kind: "query parameter",
+		lookup: func(name string) []string {
+			return r.request.URL.Query()[name]
+		},
+	})
+
+	return r
+}
+
+// Binde Header an die mit `header:"..."` markierten Felder von dst.
+// Pflicht Header werden mit `header:"X-Tenant,required"` gekennzeichnet.
+func (r *request) Headers(dst interface{}) *request {
+	r.bindings = append(r.bindings, &binding{
+		dst:  dst,
+		tag:  "header",
+		kind: "header",
+		lookup: func(name string) []string {
+			return r.request.Header[http.CanonicalHeaderKey(name)]
+		},
+	})
+
+	return r
+}
+
+// Binde Cookies an die mit `cookie:"..."` markierten Felder von dst
+func (r *request) Cookies(dst interface{}) *request {
+	r.bindings = append(r.bindings, &binding{
+		dst:  dst,
+		tag:  "cookie",
+		kind: "cookie",
+		lookup: func(name string) []string {
+			var values []string
+			for _, c := range r.request.Cookies() {
+				if c.Name == name {
+					values = append(values, c.Value)
+				}
+			}
+
+			return values
+		},
+	})
+
+	return r
+}
+
+func (b *binding) bind(e *Engine) Error {
+	v := reflect.ValueOf(b.dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		err := fmt.Errorf("Expected pointer to struct was %T", b.dst)
+		return NewError(fmt.Sprintf("Error cannot bind %vs", b.kind), err)
+	}
+
+	if err := b.bindStruct(v.Elem()); err != nil {
+		return err
+	}
+
+	return e.validateStruct(b.dst)
+}
+
+func (b *binding) bindStruct(v reflect.Value) Error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(b.tag)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := b.bindStruct(v.Field(i)); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		name, required := parseBindTag(tag, field.Name)
+		if name == "-" {
+			continue
+		}
+
+		values := b.lookup(name)
+		if len(values) == 0 {
+			if d, ok := field.Tag.Lookup("default"); ok {
+				values = []string{d}
+			}
+		}
+
+		if len(values) == 0 {
+			if required {
+				msg := fmt.Sprintf("Error missing %v %v", b.kind, name)
+				return validationFailed(msg, []FieldError{{Field: name, Tag: "required"}})
+			}
+
+			continue
+		}
+
+		if err := setField(v.Field(i), values); err != nil {
+			return NewError(fmt.Sprintf("Error cannot parse %v %v", b.kind, name), err)
+		}
+	}
+
+	return nil
+}
+
+// Zerlegt einen Tag der Form "name,required"
+func parseBindTag(tag, fieldName string) (string, bool) {
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = fieldName
+	}
+
+	required := false
+	for _, o := range parts[1:] {
+		if o == "required" {
+			required = true
+		}
+	}
+
+	return name, required
+}
+
+// Wandelt die übergebenen Werte in den Typ des Feldes um
+func setField(f reflect.Value, values []string) error {
+	if f.Kind() == reflect.Slice && !f.Addr().Type().Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(f.Type(), len(values), len(values))
+		for i, v := range values {
+			if err := setValue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+
+		f.Set(s)
+
+		return nil
+	}
+
+	return setValue(f, values[0])
+}
+
+func setValue(f reflect.Value, s string) error {
+	if f.Kind() == reflect.Ptr {
+		tmp := reflect.New(f.Type().Elem())
+		if err := setValue(tmp.Elem(), s); err != nil {
+			return err
+		}
+
+		f.Set(tmp)
+
+		return nil
+	}
+
+	if f.CanAddr() && f.Addr().Type().Implements(textUnmarshalerType) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+
+		f.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		fl, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(fl)
+	default:
+		return errors.New("Not supported field type " + f.Type().String())
+	}
+
+	return nil
+}
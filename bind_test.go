@@ -0,0 +1,212 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testQuery struct {
+	Name     string        `query:"name,required"`
+	Tags     []string      `query:"tag"`
+	Limit    int           `query:"limit" default:"10" validate:"max=100"`
+	Cute     *bool         `query:"cute"`
+	Timeout  time.Duration `query:"timeout"`
+	Since    time.Time     `query:"since"`
+	Untagged string
+}
+
+func Test_Query(t *testing.T) {
+	tc := struct {
+		URL      string
+		Expected testQuery
+	}{
+		URL: "/?name=fluffy&tag=a&tag=b&cute=true&timeout=2s&since=2016-01-02T00:00:00Z&Untagged=x",
+		Expected: testQuery{
+			Name:    "fluffy",
+			Tags:    []string{"a", "b"},
+			Limit:   10,
+			Timeout: 2 * time.Second,
+			Since:   time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	// Run test
+	{
+		req := NewRequest(t, "GET", tc.URL, &bytes.Buffer{})
+
+		var q testQuery
+		err := Request(req).Query(&q).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if q.Name != tc.Expected.Name ||
+			len(q.Tags) != 2 || q.Tags[0] != "a" || q.Tags[1] != "b" ||
+			q.Limit != tc.Expected.Limit ||
+			q.Cute == nil || !*q.Cute ||
+			q.Timeout != tc.Expected.Timeout ||
+			!q.Since.Equal(tc.Expected.Since) ||
+			q.Untagged != "" {
+			t.Fatalf("Expected %v was %v", tc.Expected, q)
+		}
+	}
+}
+
+func Test_QueryError(t *testing.T) {
+	tc := []struct {
+		URL            string
+		Expected       string
+		ExpectedFields []FieldError
+	}{
+		{
+			URL:            "/?limit=1",
+			Expected:       "Error missing query parameter name",
+			ExpectedFields: []FieldError{{Field: "name", Tag: "required"}},
+		},
+		{
+			URL:      "/?name=a&limit=many",
+			Expected: "Error cannot parse query parameter limit",
+		},
+		{
+			URL:      "/?name=a&limit=1000",
+			Expected: "Limit failed due to max",
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "GET", v.URL, &bytes.Buffer{})
+
+		var q testQuery
+		err := Request(req).Query(&q).Process()
+		if err == nil || err.Message() != v.Expected {
+			t.Fatalf("Expected %v was %v", v.Expected, err)
+		}
+
+		checkMissingField(t, err, v.ExpectedFields)
+	}
+}
+
+type testHeaders struct {
+	IfMatch  string   `header:"If-Match"`
+	Tenant   int64    `header:"x-tenant,required"`
+	Language []string `header:"Accept-Language" default:"en"`
+}
+
+type testCookies struct {
+	Session string `cookie:"session,required" validate:"len=4"`
+	Theme   string `cookie:"theme" default:"dark"`
+}
+
+func Test_HeadersCookies(t *testing.T) {
+	tc := struct {
+		ExpectedHeaders testHeaders
+		ExpectedCookies testCookies
+	}{
+		ExpectedHeaders: testHeaders{
+			IfMatch:  `"v1"`,
+			Tenant:   3,
+			Language: []string{"en"},
+		},
+		ExpectedCookies: testCookies{
+			Session: "abcd",
+			Theme:   "dark",
+		},
+	}
+
+	// Run test
+	{
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("If-Match", `"v1"`)
+		req.Header.Set("X-Tenant", "3")
+		req.AddCookie(&http.Cookie{Name: "session", Value: "abcd"})
+
+		var h testHeaders
+		var c testCookies
+		err := Request(req).Headers(&h).Cookies(&c).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if h.IfMatch != tc.ExpectedHeaders.IfMatch ||
+			h.Tenant != tc.ExpectedHeaders.Tenant ||
+			len(h.Language) != 1 || h.Language[0] != "en" {
+			t.Fatalf("Expected %v was %v", tc.ExpectedHeaders, h)
+		}
+
+		if c != tc.ExpectedCookies {
+			t.Fatalf("Expected %v was %v", tc.ExpectedCookies, c)
+		}
+	}
+}
+
+func Test_HeadersCookiesError(t *testing.T) {
+	tc := []struct {
+		Header         string
+		Cookie         string
+		Expected       string
+		ExpectedFields []FieldError
+	}{
+		{
+			Header:         "",
+			Cookie:         "abcd",
+			Expected:       "Error missing header x-tenant",
+			ExpectedFields: []FieldError{{Field: "x-tenant", Tag: "required"}},
+		},
+		{
+			Header:   "one",
+			Cookie:   "abcd",
+			Expected: "Error cannot parse header x-tenant",
+		},
+		{
+			Header:         "1",
+			Cookie:         "",
+			Expected:       "Error missing cookie session",
+			ExpectedFields: []FieldError{{Field: "session", Tag: "required"}},
+		},
+		{
+			Header:   "1",
+			Cookie:   "abc",
+			Expected: "Session failed due to len",
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		if v.Header != "" {
+			req.Header.Set("X-Tenant", v.Header)
+		}
+		if v.Cookie != "" {
+			req.AddCookie(&http.Cookie{Name: "session", Value: v.Cookie})
+		}
+
+		var h testHeaders
+		var c testCookies
+		err := Request(req).Headers(&h).Cookies(&c).Process()
+		if err == nil || err.Message() != v.Expected {
+			t.Fatalf("Expected %v was %v", v.Expected, err)
+		}
+
+		checkMissingField(t, err, v.ExpectedFields)
+	}
+}
+
+// Fehlende Pflichtwerte liefern den gleichen Fehler wie die Body Validierung
+func checkMissingField(t *testing.T, err Error, expected []FieldError) {
+	if expected == nil {
+		return
+	}
+
+	s, ok := err.(StatusError)
+	if !ok || s.Status() != http.StatusBadRequest || s.Code() != "validation_failed" {
+		t.Fatalf("Expected validation_failed was %v", err)
+	}
+
+	if fields := fieldErrors(err); !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("Expected %v was %v", expected, fields)
+	}
+}
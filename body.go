@@ -0,0 +1,125 @@
+package hrr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+type (
+	// Merkt sich die ersten limit Bytes die geschrieben werden
+	prefixBuffer struct {
+		limit int
+		data  []byte
+	}
+)
+
+// Setze die maximale Größe des Bodys für diesen Request, 0 oder negativ bedeutet
+// keine Begrenzung.
+// Größere Bodys werden mit 413 Payload Too Large abgelehnt.
+func (r *request) MaxBodySize(n int64) *request {
+	r.maxBodySize = n
+	return r
+}
+
+// Dekodiere den Body direkt aus der Verbindung ohne ihn vorher vollständig zu lesen.
+// Geloggt werden nur die ersten LogBodyPrefix Bytes des Bodys.
+func (r *request) StreamBody() *request {
+	r.streamBody = true
+	return r
+}
+
+func (r *request) streamDecodeBody() Error {
+	if err := r.checkContentLength(); err != nil {
+		return err
+	}
+
+	contentType := r.request.Header.Get("Content-Type")
+	codec, ok := r.engine.codecRegistry().byContentType(contentType)
+	if !ok {
+		return unsupportedMediaType(contentType)
+	}
+
+	prefix := &prefixBuffer{limit: r.engine.logBodyPrefix()}
+	defer func() {
+		r.body = prefix.data
+	}()
+
+	body := io.TeeReader(r.bodyReader(), prefix)
+
+	var err error
+	if r.strict && codec.Name() == JSONCodec.Name() {
+		if e := strictDecode(body, r.bodyObject); e != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(e, &tooLarge) {
+				return r.bodyReadError(e)
+			}
+
+			return e
+		}
+	} else if d, ok := codec.(StreamDecoder); ok {
+		err = d.Decode(body, r.bodyObject)
+	} else {
+		var data []byte
+		data, err = ioutil.ReadAll(body)
+		if err == nil {
+			err = codec.Unmarshal(data, r.bodyObject)
+		}
+	}
+
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return r.bodyReadError(err)
+		}
+
+		return NewError(fmt.Sprintf("Error cannot parse %v body", codec.Name()), err)
+	}
+
+	return nil
+}
+
+// Body begrenzt auf die maximale Größe
+func (r *request) bodyReader() io.Reader {
+	if r.request.Body == nil {
+		return http.NoBody
+	}
+
+	if r.maxBodySize <= 0 {
+		return r.request.Body
+	}
+
+	return http.MaxBytesReader(nil, r.request.Body, r.maxBodySize)
+}
+
+// Lehne Bodys ab deren angekündigte Größe bereits zu groß ist
+func (r *request) checkContentLength() Error {
+	if r.maxBodySize > 0 && r.request.ContentLength > r.maxBodySize {
+		return r.bodyReadError(&http.MaxBytesError{Limit: r.maxBodySize})
+	}
+
+	return nil
+}
+
+func (r *request) bodyReadError(err error) Error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		msg := fmt.Sprintf("Error body larger than %v bytes", tooLarge.Limit)
+		return NewStatusError(http.StatusRequestEntityTooLarge, "payload_too_large", msg, err)
+	}
+
+	return NewError("Error while reading Process Body", err)
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - len(b.data); n > 0 {
+		if len(p) < n {
+			n = len(p)
+		}
+		b.data = append(b.data, p[:n]...)
+	}
+
+	return len(p), nil
+}
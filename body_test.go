@@ -0,0 +1,175 @@
+package hrr
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+func Test_MaxBodySize(t *testing.T) {
+	tc := []struct {
+		Body          string
+		Limit         int64
+		ContentLength int64
+		Stream        bool
+		Expected      int
+	}{
+		{
+			Body:          `{"value": "small"}`,
+			Limit:         100,
+			ContentLength: -1,
+			Expected:      0,
+		},
+		{
+			Body:          `{"value": "` + strings.Repeat("x", 100) + `"}`,
+			Limit:         50,
+			ContentLength: -1,
+			Expected:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			Body:          `{"value": "` + strings.Repeat("x", 100) + `"}`,
+			Limit:         50,
+			ContentLength: -1,
+			Stream:        true,
+			Expected:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			Body:          `{"value": "small"}`,
+			Limit:         10,
+			ContentLength: 18,
+			Expected:      http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "POST", "/", io.NopCloser(strings.NewReader(v.Body)))
+		req.ContentLength = v.ContentLength
+
+		var body TestObject
+		r := Request(req).DecodeBody(&body).MaxBodySize(v.Limit)
+		if v.Stream {
+			r.StreamBody()
+		}
+
+		err := r.Process()
+		if v.Expected == 0 {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.Expected {
+			t.Fatalf("Expected %v was %v", v.Expected, err)
+		}
+	}
+}
+
+func Test_MaxBodySizeGlobal(t *testing.T) {
+	tc := struct {
+		Body     string
+		Limit    int64
+		Expected string
+	}{
+		Body:     strings.Repeat("x", 20),
+		Limit:    10,
+		Expected: "Error body larger than 10 bytes",
+	}
+
+	// Run test
+	{
+		old := MaxBodySize
+		MaxBodySize = tc.Limit
+		defer func() { MaxBodySize = old }()
+
+		req := NewRequest(t, "POST", "/", io.NopCloser(strings.NewReader(tc.Body)))
+		err := Request(req).Process()
+		if err == nil || err.Message() != tc.Expected {
+			t.Fatalf("Expected %v was %v", tc.Expected, err)
+		}
+	}
+}
+
+func Test_MaxBodySizeEngine(t *testing.T) {
+	tc := []struct {
+		Engine   *Engine
+		Expected string
+	}{
+		// Eine leere Engine verwendet die globale Grenze
+		{Engine: &Engine{}, Expected: "Error body larger than 10 bytes"},
+		{Engine: &Engine{MaxBodySize: 15}, Expected: "Error body larger than 15 bytes"},
+		{Engine: &Engine{MaxBodySize: -1}},
+	}
+
+	// Run test
+	for i, v := range tc {
+		old := MaxBodySize
+		MaxBodySize = 10
+
+		req := NewRequest(t, "POST", "/", io.NopCloser(strings.NewReader(strings.Repeat("x", 20))))
+		err := v.Engine.Request(req).Process()
+		MaxBodySize = old
+
+		if v.Expected == "" {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+			continue
+		}
+
+		if err == nil || err.Message() != v.Expected {
+			t.Fatalf("%v: Expected %v was %v", i, v.Expected, err)
+		}
+	}
+}
+
+func Test_StreamBody(t *testing.T) {
+	tc := struct {
+		Count          int
+		LogPrefix      int
+		ExpectedLogged string
+	}{
+		Count:          1000,
+		LogPrefix:      12,
+		ExpectedLogged: `[{"value":"0`,
+	}
+
+	// Run test
+	{
+		var buf bytes.Buffer
+		buf.WriteString("[")
+		for i := 0; i < tc.Count; i++ {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(`{"value":"` + strings.Repeat("0", 3) + `"}`)
+		}
+		buf.WriteString("]")
+
+		logger, mock := test.NewNullLogger()
+		e := NewEngine()
+		e.Logger = logger
+		e.LogBodyPrefix = tc.LogPrefix
+
+		req := NewRequest(t, "POST", "/", &buf)
+
+		var body []TestObject
+		err := e.Request(req).Log().DecodeBody(&body).StreamBody().Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(body) != tc.Count {
+			t.Fatalf("Expected %v was %v", tc.Count, len(body))
+		}
+
+		if logged := mock.LastEntry().Data["body"]; logged != tc.ExpectedLogged {
+			t.Fatalf("Expected %v was %v", tc.ExpectedLogged, logged)
+		}
+	}
+}
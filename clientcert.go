@@ -0,0 +1,129 @@
+package hrr
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+type (
+	// Regeln für ClientCertAuth, ein Zertifikat wird akzeptiert wenn eine Regel passt.
+	// Subject, DNS Namen, E-Mail Adressen und URIs werden nur bei Zertifikaten geprüft
+	// die der TLS Server gegen ClientCAs verifiziert hat. Fingerprints (SHA-256, hex)
+	// pinnen ein Zertifikat und gelten auch ohne verifizierte Kette.
+	ClientCertConfig struct {
+		Subjects     []string
+		DNSNames     []string
+		Emails       []string
+		URIs         []string
+		Fingerprints []string
+
+		// Optionale eigene Prüfung, wird aufgerufen wenn keine Regel gepasst hat.
+		// Liefert den Aufrufer oder nil wenn das Zertifikat abgelehnt wird.
+		Verify func(cert *x509.Certificate, verified bool) (*Identity, error)
+	}
+)
+
+var (
+	errMissingClientCert = errors.New("Missing client certificate")
+	errInvalidClientCert = errors.New("Client certificate not allowed")
+)
+
+// Aktiviere Auth mit TLS Client Zertifikaten. Der Aufrufer erhält den Common Name
+// als ID, ohne Common Name den ersten Subject Alternative Name.
+func (r *request) ClientCertAuth(config ClientCertConfig) *request {
+	r.authenticate = func() (*Identity, Error) {
+		if r.request.TLS == nil || len(r.request.TLS.PeerCertificates) == 0 {
+			return nil, Unauthorized("Unauthorized client certificate", errMissingClientCert)
+		}
+
+		cert := r.request.TLS.PeerCertificates[0]
+		verified := len(r.request.TLS.VerifiedChains) > 0
+
+		if config.matches(cert, verified) {
+			return certIdentity(cert), nil
+		}
+
+		if config.Verify != nil {
+			id, err := config.Verify(cert, verified)
+			if err != nil {
+				return nil, Internal("Error cannot verify client certificate", err)
+			}
+
+			if id != nil {
+				return id, nil
+			}
+		}
+
+		return nil, Unauthorized("Unauthorized client certificate", errInvalidClientCert)
+	}
+
+	return r
+}
+
+// SHA-256 Fingerprint eines Zertifikats als hex
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c ClientCertConfig) matches(cert *x509.Certificate, verified bool) bool {
+	fingerprint := CertFingerprint(cert)
+	for _, v := range c.Fingerprints {
+		v = strings.ToLower(strings.Replace(v, ":", "", -1))
+		if v == fingerprint {
+			return true
+		}
+	}
+
+	if !verified {
+		return false
+	}
+
+	if cert.Subject.CommonName != "" && contains(c.Subjects, cert.Subject.CommonName) {
+		return true
+	}
+
+	for _, v := range cert.DNSNames {
+		if contains(c.DNSNames, v) {
+			return true
+		}
+	}
+
+	for _, v := range cert.EmailAddresses {
+		if contains(c.Emails, v) {
+			return true
+		}
+	}
+
+	for _, v := range cert.URIs {
+		if contains(c.URIs, v.String()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func certIdentity(cert *x509.Certificate) *Identity {
+	id := cert.Subject.CommonName
+	switch {
+	case id != "":
+	case len(cert.DNSNames) > 0:
+		id = cert.DNSNames[0]
+	case len(cert.EmailAddresses) > 0:
+		id = cert.EmailAddresses[0]
+	case len(cert.URIs) > 0:
+		id = cert.URIs[0].String()
+	}
+
+	return &Identity{
+		ID: id,
+		Claims: map[string]interface{}{
+			"subject":     cert.Subject.String(),
+			"fingerprint": CertFingerprint(cert),
+		},
+	}
+}
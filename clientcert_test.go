@@ -0,0 +1,120 @@
+package hrr
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newClientCert(t *testing.T, cn string, dnsNames ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func Test_ClientCertAuth(t *testing.T) {
+	billing := newClientCert(t, "billing")
+	search := newClientCert(t, "", "search.internal")
+	pinned := newClientCert(t, "pinned")
+	unknown := newClientCert(t, "unknown")
+	broken := newClientCert(t, "broken")
+
+	// Fingerprint mit Doppelpunkten und Großbuchstaben
+	fp := CertFingerprint(pinned)
+	var parts []string
+	for i := 0; i < len(fp); i += 2 {
+		parts = append(parts, strings.ToUpper(fp[i:i+2]))
+	}
+
+	config := ClientCertConfig{
+		Subjects:     []string{"billing"},
+		DNSNames:     []string{"search.internal"},
+		Fingerprints: []string{strings.Join(parts, ":")},
+		Verify: func(cert *x509.Certificate, verified bool) (*Identity, error) {
+			if cert.Subject.CommonName == "broken" {
+				return nil, errors.New("Store offline")
+			}
+
+			if verified && cert.Subject.CommonName == "callback" {
+				return &Identity{ID: "callback"}, nil
+			}
+
+			return nil, nil
+		},
+	}
+
+	tc := []struct {
+		Cert           *x509.Certificate
+		Verified       bool
+		ExpectedID     string
+		ExpectedStatus int
+	}{
+		{Cert: billing, Verified: true, ExpectedID: "billing"},
+		{Cert: search, Verified: true, ExpectedID: "search.internal"},
+		{Cert: pinned, ExpectedID: "pinned"},
+		{Cert: newClientCert(t, "callback"), Verified: true, ExpectedID: "callback"},
+		// Subject passt, aber die Kette wurde nicht verifiziert
+		{Cert: billing, ExpectedStatus: http.StatusUnauthorized},
+		{Cert: unknown, Verified: true, ExpectedStatus: http.StatusUnauthorized},
+		{Cert: broken, Verified: true, ExpectedStatus: http.StatusInternalServerError},
+		{ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		if v.Cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{v.Cert}}
+			if v.Verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{v.Cert}}
+			}
+		}
+
+		err := Request(req).ClientCertAuth(config).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+
+			if id := Principal(req); id == nil || id.ID != v.ExpectedID {
+				t.Fatalf("%v: Expected %v was %v", i, v.ExpectedID, id)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+}
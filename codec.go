@@ -0,0 +1,306 @@
+package hrr
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Kodiert und dekodiert Bodys eines Media Types
+	Codec interface {
+		// Name für Fehlermeldungen, z.B. JSON
+		Name() string
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(data []byte, v interface{}) error
+	}
+
+	// Optionale Erweiterung von Codec um direktes Dekodieren aus einem Reader
+	StreamDecoder interface {
+		Decode(r io.Reader, v interface{}) error
+	}
+
+	// Codec aus einzelnen Funktionen, ohne DecodeFunc wird der Reader
+	// vollständig gelesen und an UnmarshalFunc übergeben
+	CodecFuncs struct {
+		CodecName     string
+		MarshalFunc   func(v interface{}) ([]byte, error)
+		UnmarshalFunc func(data []byte, v interface{}) error
+		DecodeFunc    func(r io.Reader, v interface{}) error
+	}
+
+	// Codecs nach Media Type, der zuerst registrierte Codec ist der Standard
+	codecRegistry struct {
+		mu     sync.RWMutex
+		types  []string
+		codecs map[string]Codec
+	}
+
+	acceptRange struct {
+		mediaType string
+		q         float64
+	}
+)
+
+// Mitgelieferte Codecs
+var (
+	JSONCodec Codec = CodecFuncs{"JSON", json.Marshal, json.Unmarshal, func(r io.Reader, v interface{}) error {
+		return json.NewDecoder(r).Decode(v)
+	}}
+	XMLCodec Codec = CodecFuncs{"XML", xml.Marshal, xml.Unmarshal, func(r io.Reader, v interface{}) error {
+		return xml.NewDecoder(r).Decode(v)
+	}}
+	MsgpackCodec Codec = CodecFuncs{"MessagePack", msgpack.Marshal, msgpack.Unmarshal, func(r io.Reader, v interface{}) error {
+		return msgpack.NewDecoder(r).Decode(v)
+	}}
+	YAMLCodec Codec = CodecFuncs{"YAML", yaml.Marshal, yaml.Unmarshal, func(r io.Reader, v interface{}) error {
+		return yaml.NewDecoder(r).Decode(v)
+	}}
+	CBORCodec Codec = CodecFuncs{"CBOR", cbor.Marshal, cbor.Unmarshal, func(r io.Reader, v interface{}) error {
+		return cbor.NewDecoder(r).Decode(v)
+	}}
+)
+
+// Codecs der Funktionen Request und Response
+var codecs = newCodecRegistry()
+
+// Registriere einen Codec für Request und Response.
+// Ein bereits registrierter Media Type wird überschrieben.
+func RegisterCodec(mediaType string, c Codec) {
+	codecs.register(mediaType, c)
+}
+
+// Registriere einen Codec für Requests und Responses der Engine.
+// Eine Engine ohne eigene Codecs erhält dabei eine Kopie der globalen Codecs,
+// diese werden nicht verändert.
+func (e *Engine) RegisterCodec(mediaType string, c Codec) {
+	if e.codecs == nil {
+		e.codecs = codecs.clone()
+	}
+
+	e.codecs.register(mediaType, c)
+}
+
+func (e *Engine) codecRegistry() *codecRegistry {
+	if e.codecs == nil {
+		return codecs
+	}
+
+	return e.codecs
+}
+
+func (c CodecFuncs) Name() string {
+	return c.CodecName
+}
+
+func (c CodecFuncs) Marshal(v interface{}) ([]byte, error) {
+	return c.MarshalFunc(v)
+}
+
+func (c CodecFuncs) Unmarshal(data []byte, v interface{}) error {
+	return c.UnmarshalFunc(data, v)
+}
+
+func (c CodecFuncs) Decode(r io.Reader, v interface{}) error {
+	if c.DecodeFunc != nil {
+		return c.DecodeFunc(r, v)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return c.UnmarshalFunc(data, v)
+}
+
+func newCodecRegistry() *codecRegistry {
+	c := &codecRegistry{codecs: map[string]Codec{}}
+	c.register("application/json", JSONCodec)
+	c.register("application/xml", XMLCodec)
+	c.register("text/xml", XMLCodec)
+	c.register("application/msgpack", MsgpackCodec)
+	c.register("application/x-msgpack", MsgpackCodec)
+	c.register("application/yaml", YAMLCodec)
+	c.register("application/x-yaml", YAMLCodec)
+	c.register("application/cbor", CBORCodec)
+
+	return c
+}
+
+func (c *codecRegistry) clone() *codecRegistry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	clone := &codecRegistry{
+		types:  append([]string(nil), c.types...),
+		codecs: make(map[string]Codec, len(c.codecs)),
+	}
+	for k, v := range c.codecs {
+		clone.codecs[k] = v
+	}
+
+	return clone
+}
+
+func (c *codecRegistry) register(mediaType string, codec Codec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mediaType = strings.ToLower(mediaType)
+	if _, ok := c.codecs[mediaType]; !ok {
+		c.types = append(c.types, mediaType)
+	}
+
+	c.codecs[mediaType] = codec
+}
+
+// Codec für einen Content-Type Header, ohne Angabe wird der Standard Codec verwendet
+func (c *codecRegistry) byContentType(contentType string) (Codec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if contentType == "" {
+		return c.codecs[c.types[0]], true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	return c.lookup(mediaType)
+}
+
+// Suche Codec für den Accept Header, liefert den gewählten Media Type und Codec
+func (c *codecRegistry) negotiate(accept string) (string, Codec, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if strings.TrimSpace(accept) == "" {
+		return c.types[0], c.codecs[c.types[0]], true
+	}
+
+	ranges := parseAccept(accept)
+
+	// Mit q=0 ausgeschlossene Media Types gelten auch für Wildcards
+	excluded := map[string]bool{}
+	for _, a := range ranges {
+		if a.q <= 0 {
+			excluded[strings.ToLower(a.mediaType)] = true
+		}
+	}
+
+	for _, a := range ranges {
+		if a.q <= 0 {
+			continue
+		}
+
+		switch {
+		case a.mediaType == "*/*":
+			for _, t := range c.types {
+				if !excluded[t] {
+					return t, c.codecs[t], true
+				}
+			}
+		case strings.HasSuffix(a.mediaType, "/*"):
+			prefix := strings.TrimSuffix(a.mediaType, "*")
+			for _, t := range c.types {
+				if strings.HasPrefix(t, prefix) && !excluded[t] {
+					return t, c.codecs[t], true
+				}
+			}
+		default:
+			// Nur registrierte Media Types, sonst würde z.B. application/xhtml+xml
+			// als Content-Type gesendet obwohl der Body reines XML ist
+			mediaType := strings.ToLower(a.mediaType)
+			if codec, ok := c.codecs[mediaType]; ok {
+				return mediaType, codec, true
+			}
+		}
+	}
+
+	return "", nil, false
+}
+
+// Sucht einen Codec für einen Request Body, Media Types mit Suffix wie
+// application/vnd.monster+json verwenden den Codec von application/json
+func (c *codecRegistry) lookup(mediaType string) (Codec, bool) {
+	mediaType = strings.ToLower(mediaType)
+	if codec, ok := c.codecs[mediaType]; ok {
+		return codec, true
+	}
+
+	i := strings.LastIndex(mediaType, "+")
+	if i < 0 {
+		return nil, false
+	}
+
+	codec, ok := c.codecs["application/"+mediaType[i+1:]]
+
+	return codec, ok
+}
+
+// Zerlegt einen Accept Header und sortiert nach q-Wert und Genauigkeit
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+
+		return specificity(ranges[i].mediaType) > specificity(ranges[j].mediaType)
+	})
+
+	return ranges
+}
+
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+
+	return 2
+}
+
+// Fehler wenn kein Codec für den Content-Type existiert
+func unsupportedMediaType(contentType string) requestError {
+	return NewStatusError(http.StatusUnsupportedMediaType, "unsupported_media_type",
+		"Error unsupported content type "+contentType, nil)
+}
+
+// Fehler wenn kein Codec für den Accept Header existiert
+func notAcceptable(accept string) requestError {
+	return NewStatusError(http.StatusNotAcceptable, "not_acceptable",
+		"Error cannot respond with "+accept, nil)
+}
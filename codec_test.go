@@ -0,0 +1,231 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+type codecMonster struct {
+	Name     string `json:"name" xml:"name" yaml:"name" msgpack:"name" cbor:"name"`
+	Cuteness int    `json:"cuteness" xml:"cuteness" yaml:"cuteness" msgpack:"cuteness" cbor:"cuteness"`
+}
+
+func Test_DecodeBodyContentType(t *testing.T) {
+	tc := []struct {
+		ContentType string
+		Body        []byte
+	}{
+		{
+			ContentType: "",
+			Body:        []byte(`{"name": "Fluffy", "cuteness": 100}`),
+		},
+		{
+			ContentType: "application/vnd.monster+json; charset=utf-8",
+			Body:        []byte(`{"name": "Fluffy", "cuteness": 100}`),
+		},
+		{
+			ContentType: "application/xml",
+			Body:        []byte(`<codecMonster><name>Fluffy</name><cuteness>100</cuteness></codecMonster>`),
+		},
+		{
+			ContentType: "application/yaml",
+			Body:        []byte("name: Fluffy\ncuteness: 100\n"),
+		},
+		{
+			ContentType: "application/msgpack",
+			Body:        mustMarshal(t, MsgpackCodec, codecMonster{"Fluffy", 100}),
+		},
+		{
+			ContentType: "application/cbor",
+			Body:        mustMarshal(t, CBORCodec, codecMonster{"Fluffy", 100}),
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "POST", "/", bytes.NewBuffer(v.Body))
+		req.Header.Set("Content-Type", v.ContentType)
+
+		var body codecMonster
+		err := Request(req).DecodeBody(&body).Process()
+		if err != nil {
+			t.Fatalf("%v: %v", v.ContentType, err)
+		}
+
+		if body.Name != "Fluffy" || body.Cuteness != 100 {
+			t.Fatalf("%v: Expected Fluffy was %v", v.ContentType, body)
+		}
+	}
+}
+
+func Test_DecodeBodyUnsupportedMediaType(t *testing.T) {
+	tc := struct {
+		ContentType    string
+		ExpectedStatus int
+	}{
+		ContentType:    "application/x-protobuf",
+		ExpectedStatus: http.StatusUnsupportedMediaType,
+	}
+
+	// Run test
+	{
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString("x"))
+		req.Header.Set("Content-Type", tc.ContentType)
+
+		var body codecMonster
+		err := Request(req).DecodeBody(&body).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, err)
+		}
+	}
+}
+
+func Test_ResponseDataAccept(t *testing.T) {
+	tc := []struct {
+		Accept              string
+		ExpectedStatus      int
+		ExpectedContentType string
+	}{
+		{
+			Accept:              "",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json",
+		},
+		{
+			Accept:              "text/html, application/xml;q=0.9, application/json;q=0.8",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/xml",
+		},
+		{
+			Accept:              "application/yaml;q=0.5, application/cbor",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/cbor",
+		},
+		{
+			Accept:              "text/html, */*;q=0.1",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json",
+		},
+		{
+			Accept:              "text/html,application/xhtml+xml,image/webp,*/*;q=0.8",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json",
+		},
+		{
+			Accept:              "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/xml",
+		},
+		{
+			Accept:              "application/vnd.monster+json",
+			ExpectedStatus:      http.StatusNotAcceptable,
+			ExpectedContentType: "",
+		},
+		{
+			Accept:              "application/json;q=0, */*",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/xml",
+		},
+		{
+			Accept:              "application/xml;q=0, text/xml;q=0, application/*",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/json",
+		},
+		{
+			Accept:              "application/json;q=0, application/xml;q=0, application/*",
+			ExpectedStatus:      http.StatusOK,
+			ExpectedContentType: "application/msgpack",
+		},
+		{
+			Accept:              "application/json;q=0, text/html",
+			ExpectedStatus:      http.StatusNotAcceptable,
+			ExpectedContentType: "",
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("Accept", v.Accept)
+		resp := httptest.NewRecorder()
+
+		Response(resp, req).Data(func() (interface{}, Error) {
+			return codecMonster{"Fluffy", 100}, nil
+		})
+
+		if resp.Code != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", v.Accept, v.ExpectedStatus, resp.Code)
+		}
+
+		if v.ExpectedContentType == "" {
+			continue
+		}
+
+		if ct := resp.Header().Get("Content-Type"); ct != v.ExpectedContentType {
+			t.Fatalf("%v: Expected %v was %v", v.Accept, v.ExpectedContentType, ct)
+		}
+	}
+}
+
+func Test_EngineRegisterCodec(t *testing.T) {
+	tc := []struct {
+		Engine       *Engine
+		MediaType    string
+		ExpectedBody string
+	}{
+		{Engine: NewEngine(), MediaType: "text/plain", ExpectedBody: "FLUFFY"},
+		// Eine leere Engine darf die globalen Codecs nicht verändern
+		{Engine: &Engine{}, MediaType: "text/x-fluffy", ExpectedBody: "FLUFFY"},
+	}
+
+	// Run test
+	for _, v := range tc {
+		e := v.Engine
+		e.RegisterCodec(v.MediaType, CodecFuncs{
+			CodecName: "Text",
+			MarshalFunc: func(v interface{}) ([]byte, error) {
+				return []byte(strings.ToUpper(v.(string))), nil
+			},
+		})
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("Accept", v.MediaType)
+		resp := httptest.NewRecorder()
+
+		e.Response(resp, req).Data(func() (interface{}, Error) {
+			return "fluffy", nil
+		})
+
+		if resp.Body.String() != v.ExpectedBody {
+			t.Fatalf("Expected %v was %v", v.ExpectedBody, resp.Body.String())
+		}
+
+		// Andere Engines kennen den Codec nicht
+		req.Header.Set("Accept", v.MediaType)
+		resp = httptest.NewRecorder()
+		Response(resp, req).Data(func() (interface{}, Error) {
+			return "fluffy", nil
+		})
+
+		if resp.Code != http.StatusNotAcceptable {
+			t.Fatalf("Expected %v was %v", http.StatusNotAcceptable, resp.Code)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, c Codec, v interface{}) []byte {
+	b, err := c.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
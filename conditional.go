@@ -0,0 +1,186 @@
+package hrr
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type (
+	// Liefert die aktuelle Version einer Ressource. etag darf mit oder ohne
+	// Anführungszeichen angegeben werden, leere Werte werden ignoriert.
+	VersionFunc func() (etag string, modified time.Time, err Error)
+)
+
+var errPreconditionFailed = errors.New("Precondition failed")
+
+// ETag für die übergebenen Daten, schwache ETags beginnen mit W/
+func NewETag(data []byte, weak bool) string {
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
+
+// Berechne einen starken ETag aus dem kodierten Body. Bei GET und HEAD wird
+// 304 Not Modified gesendet wenn If-None-Match passt.
+func (r *response) ETag() *response {
+	r.autoETag = true
+	r.weakETag = false
+	return r
+}
+
+// Wie ETag, aber mit schwachem ETag, z.B. wenn der Body nicht Byte für Byte gleich bleibt
+func (r *response) WeakETag() *response {
+	r.autoETag = true
+	r.weakETag = true
+	return r
+}
+
+// Ermittle ETag und Last-Modified vor dem Aufruf der Data Funktion. Bei passendem
+// If-None-Match oder If-Modified-Since wird 304 gesendet ohne die Daten zu laden.
+func (r *response) Version(fn VersionFunc) *response {
+	r.version = fn
+	return r
+}
+
+// Setze Last-Modified, wird für If-Modified-Since verwendet
+func (r *response) LastModified(t time.Time) *response {
+	r.lastModified = t
+	return r
+}
+
+// Prüfe If-Match und If-Unmodified-Since gegen die aktuelle Version der Ressource.
+// Passt die Version nicht liefert Process 412 Precondition Failed, so gehen
+// parallele Änderungen nicht verloren. Mit If-None-Match: * wird nur angelegt,
+// bei GET und HEAD prüft If-None-Match erst die Antwort.
+func (r *request) Precondition(fn VersionFunc) *request {
+	r.precondition = fn
+	return r
+}
+
+func (r *request) checkPrecondition() Error {
+	etag, modified, err := r.precondition()
+	if err != nil {
+		return err
+	}
+	etag = quoteETag(etag)
+
+	h := r.request.Header
+	if ifMatch := h.Get("If-Match"); ifMatch != "" {
+		if !matchETag(ifMatch, etag, false) {
+			return preconditionFailed("If-Match")
+		}
+	} else if since, ok := parseHTTPTime(h.Get("If-Unmodified-Since")); ok && !modified.IsZero() {
+		if modified.Truncate(time.Second).After(since) {
+			return preconditionFailed("If-Unmodified-Since")
+		}
+	}
+
+	// Bei GET und HEAD sendet die Antwort 304 Not Modified
+	method := r.request.Method
+	if ifNoneMatch := h.Get("If-None-Match"); ifNoneMatch != "" && method != http.MethodGet && method != http.MethodHead {
+		if matchETag(ifNoneMatch, etag, true) {
+			return preconditionFailed("If-None-Match")
+		}
+	}
+
+	return nil
+}
+
+func preconditionFailed(header string) requestError {
+	return NewStatusError(http.StatusPreconditionFailed, "precondition_failed",
+		"Error precondition "+header+" failed", errPreconditionFailed)
+}
+
+// Setze ETag und Last-Modified Header, liefert true wenn 304 gesendet werden kann
+func (r *response) validators(etag string, modified time.Time) bool {
+	if etag != "" {
+		r.response.Header().Set("ETag", etag)
+	}
+
+	if !modified.IsZero() {
+		r.response.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	return r.notModified(etag, modified)
+}
+
+func (r *response) notModified(etag string, modified time.Time) bool {
+	if r.request.Method != http.MethodGet && r.request.Method != http.MethodHead {
+		return false
+	}
+
+	h := r.request.Header
+	if ifNoneMatch := h.Get("If-None-Match"); ifNoneMatch != "" {
+		return matchETag(ifNoneMatch, etag, true)
+	}
+
+	since, ok := parseHTTPTime(h.Get("If-Modified-Since"))
+	if !ok || modified.IsZero() {
+		return false
+	}
+
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// Sende 304 Not Modified ohne Body
+func (r *response) sendNotModified() {
+	r.response.WriteHeader(http.StatusNotModified)
+	r.finish()
+}
+
+// Vergleicht einen If-Match oder If-None-Match Header mit etag.
+// Der schwache Vergleich ignoriert W/ (RFC 7232 2.3.2).
+func matchETag(header, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if weak {
+			if strings.TrimPrefix(v, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+			continue
+		}
+
+		if !strings.HasPrefix(v, "W/") && !strings.HasPrefix(etag, "W/") && v == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+
+	return `"` + etag + `"`
+}
+
+func parseHTTPTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
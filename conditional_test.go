@@ -0,0 +1,205 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+func Test_ResponseETag(t *testing.T) {
+	data := map[string]string{"name": "Gruffalo"}
+	strong := NewETag([]byte(`{"name":"Gruffalo"}`), false)
+	weak := NewETag([]byte(`{"name":"Gruffalo"}`), true)
+
+	tc := []struct {
+		Method         string
+		Weak           bool
+		IfNoneMatch    string
+		ExpectedStatus int
+		ExpectedETag   string
+	}{
+		{Method: "GET", ExpectedStatus: http.StatusOK, ExpectedETag: strong},
+		{Method: "GET", IfNoneMatch: strong, ExpectedStatus: http.StatusNotModified, ExpectedETag: strong},
+		{Method: "GET", IfNoneMatch: `"other", ` + strong, ExpectedStatus: http.StatusNotModified, ExpectedETag: strong},
+		{Method: "GET", IfNoneMatch: `"other"`, ExpectedStatus: http.StatusOK, ExpectedETag: strong},
+		{Method: "GET", IfNoneMatch: "*", ExpectedStatus: http.StatusNotModified, ExpectedETag: strong},
+		{Method: "GET", Weak: true, IfNoneMatch: strong, ExpectedStatus: http.StatusNotModified, ExpectedETag: weak},
+		{Method: "HEAD", Weak: true, IfNoneMatch: weak, ExpectedStatus: http.StatusNotModified, ExpectedETag: weak},
+		{Method: "POST", IfNoneMatch: strong, ExpectedStatus: http.StatusOK, ExpectedETag: strong},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, v.Method, "/", &bytes.Buffer{})
+		if v.IfNoneMatch != "" {
+			req.Header.Set("If-None-Match", v.IfNoneMatch)
+		}
+		resp := httptest.NewRecorder()
+
+		r := Response(resp, req).ETag()
+		if v.Weak {
+			r = r.WeakETag()
+		}
+		r.Data(func() (interface{}, Error) {
+			return data, nil
+		})
+
+		if resp.Code != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, resp.Code)
+		}
+
+		if resp.Header().Get("ETag") != v.ExpectedETag {
+			t.Fatalf("%v: Expected ETag %v was %v", i, v.ExpectedETag, resp.Header().Get("ETag"))
+		}
+
+		if v.ExpectedStatus == http.StatusNotModified && resp.Body.Len() != 0 {
+			t.Fatalf("%v: Expected empty body was %v", i, resp.Body)
+		}
+	}
+}
+
+func Test_ResponseVersion(t *testing.T) {
+	modified := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		Header         http.Header
+		ExpectedStatus int
+		ExpectedLoads  int
+	}{
+		{Header: http.Header{}, ExpectedStatus: http.StatusOK, ExpectedLoads: 1},
+		{Header: http.Header{"If-None-Match": {`"v3"`}}, ExpectedStatus: http.StatusNotModified},
+		{Header: http.Header{"If-None-Match": {`"v2"`}}, ExpectedStatus: http.StatusOK, ExpectedLoads: 1},
+		{Header: http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, ExpectedStatus: http.StatusNotModified},
+		{Header: http.Header{"If-Modified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, ExpectedStatus: http.StatusOK, ExpectedLoads: 1},
+		// If-None-Match hat Vorrang vor If-Modified-Since
+		{
+			Header: http.Header{
+				"If-None-Match":     {`"v2"`},
+				"If-Modified-Since": {modified.Format(http.TimeFormat)},
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedLoads:  1,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monster/1", &bytes.Buffer{})
+		req.Header = v.Header
+		resp := httptest.NewRecorder()
+
+		loads := 0
+		Response(resp, req).
+			Version(func() (string, time.Time, Error) {
+				return "v3", modified.Add(500 * time.Millisecond), nil
+			}).
+			Data(func() (interface{}, Error) {
+				loads++
+				return map[string]int{"id": 1}, nil
+			})
+
+		if resp.Code != v.ExpectedStatus || loads != v.ExpectedLoads {
+			t.Fatalf("%v: Expected %v with %v loads was %v with %v loads", i, v.ExpectedStatus, v.ExpectedLoads, resp.Code, loads)
+		}
+
+		if resp.Header().Get("ETag") != `"v3"` || resp.Header().Get("Last-Modified") != modified.Format(http.TimeFormat) {
+			t.Fatalf("%v: Expected validators was %v", i, resp.Header())
+		}
+	}
+}
+
+func Test_ResponseVersionError(t *testing.T) {
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/v0/monster/1", &bytes.Buffer{})
+		resp := httptest.NewRecorder()
+
+		Response(resp, req).
+			Version(func() (string, time.Time, Error) {
+				return "v3", time.Now(), nil
+			}).
+			Data(func() (interface{}, Error) {
+				return nil, NotFound("Monster not found", nil)
+			})
+
+		if resp.Code != http.StatusNotFound {
+			t.Fatalf("Expected %v was %v", http.StatusNotFound, resp.Code)
+		}
+
+		if resp.Header().Get("ETag") != "" || resp.Header().Get("Last-Modified") != "" {
+			t.Fatalf("Expected no validators was %v", resp.Header())
+		}
+	}
+}
+
+func Test_RequestPrecondition(t *testing.T) {
+	modified := time.Date(2017, 5, 1, 12, 0, 0, 0, time.UTC)
+	exists := func() (string, time.Time, Error) {
+		return "v3", modified, nil
+	}
+	missing := func() (string, time.Time, Error) {
+		return "", time.Time{}, nil
+	}
+
+	tc := []struct {
+		Method         string
+		Version        VersionFunc
+		Header         http.Header
+		ExpectedStatus int
+	}{
+		{Version: exists, Header: http.Header{}},
+		{Version: exists, Header: http.Header{"If-Match": {`"v3"`}}},
+		{Version: exists, Header: http.Header{"If-Match": {`"v1", "v3"`}}},
+		{Version: exists, Header: http.Header{"If-Match": {"*"}}},
+		{Version: exists, Header: http.Header{"If-Match": {`"v2"`}}, ExpectedStatus: http.StatusPreconditionFailed},
+		// If-Match verwendet den starken Vergleich
+		{Version: exists, Header: http.Header{"If-Match": {`W/"v3"`}}, ExpectedStatus: http.StatusPreconditionFailed},
+		{Version: missing, Header: http.Header{"If-Match": {"*"}}, ExpectedStatus: http.StatusPreconditionFailed},
+		{Version: exists, Header: http.Header{"If-Unmodified-Since": {modified.Format(http.TimeFormat)}}},
+		{Version: exists, Header: http.Header{"If-Unmodified-Since": {modified.Add(-time.Hour).Format(http.TimeFormat)}}, ExpectedStatus: http.StatusPreconditionFailed},
+		{Version: exists, Header: http.Header{"If-None-Match": {"*"}}, ExpectedStatus: http.StatusPreconditionFailed},
+		{Version: missing, Header: http.Header{"If-None-Match": {"*"}}},
+		{Version: exists, Header: http.Header{"If-None-Match": {`"v3"`}}, ExpectedStatus: http.StatusPreconditionFailed},
+		// GET und HEAD überlassen If-None-Match der Antwort
+		{Method: "GET", Version: exists, Header: http.Header{"If-None-Match": {`"v3"`}}},
+		{Method: "HEAD", Version: exists, Header: http.Header{"If-None-Match": {"*"}}},
+		{Method: "GET", Version: exists, Header: http.Header{"If-Match": {`"v2"`}}, ExpectedStatus: http.StatusPreconditionFailed},
+		{
+			Version: func() (string, time.Time, Error) {
+				return "", time.Time{}, NotFound("Monster not found", nil)
+			},
+			Header:         http.Header{"If-Match": {`"v3"`}},
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		method := "PUT"
+		if v.Method != "" {
+			method = v.Method
+		}
+
+		req := NewRequest(t, method, "/v0/monster/1", &bytes.Buffer{})
+		req.Header = v.Header
+
+		err := Request(req).Precondition(v.Version).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package hrr
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	// Konfiguration für Requests und Responses einer API.
+	// Mehrere Engines können unabhängig voneinander in einem Programm verwendet werden.
+	// Die Felder sollten vor dem ersten Request gesetzt und danach nicht mehr geändert werden.
+	// Leere Felder von Logger, ValidatorTagName, AuthRealm, MaxBodySize und
+	// LogBodyPrefix verwenden die globale Konfiguration, eine leere Engine{} ist
+	// daher nutzbar. NewEngine wird empfohlen.
+	Engine struct {
+		Logger         *logrus.Logger
+		LogAllRequests bool
+
+		// Tag Name für Validierungs Regeln, nur vor dem ersten Aufruf von Validator änderbar
+		ValidatorTagName string
+
+		// Auth Funktion die verwendet wird wenn BaseAuth nicht aufgerufen wurde.
+		// Ohne DefaultAuth sind Requests ohne eigene Anmeldung immer anonym.
+		DefaultAuth func(string, string) (bool, error)
+		AuthRealm   string
+
+		// Sende Fehler als application/problem+json (RFC 7807)
+		ProblemDetails  bool
+		ProblemTypeBase string
+
+		// Erzeugt den JSON Body einer Fehler Antwort, hat Vorrang vor ProblemDetails.
+		// id ist die ID unter der der Fehler geloggt wurde. Code und Feld Fehler
+		// sind über StatusError und ValidationError erreichbar.
+		FormatError func(r *http.Request, err Error, status int, id string) interface{}
+
+		// Maximale Größe eines Bodys in Bytes, 0 verwendet die globale Konfiguration,
+		// ein negativer Wert bedeutet keine Begrenzung
+		MaxBodySize int64
+		// Anzahl Bytes eines gestreamten Bodys die geloggt werden, 0 verwendet die
+		// globale Konfiguration, ein negativer Wert loggt keinen Body
+		LogBodyPrefix int
+		// Dekodiere alle JSON Bodys streng, siehe Strict
+		StrictDecoding bool
+
+		// Erzeugt die ID unter der ein Fehler geloggt wird
+		NewLogID func() (string, error)
+
+		validatorOnce sync.Once
+		validator     *validator.Validate
+		codecs        *codecRegistry
+	}
+)
+
+// Neue Engine mit Standard Konfiguration
+func NewEngine() *Engine {
+	return &Engine{
+		Logger:           logrus.New(),
+		ValidatorTagName: "validate",
+		AuthRealm:        "Restricted",
+		MaxBodySize:      10 << 20,
+		LogBodyPrefix:    1024,
+		NewLogID:         newLogID,
+		codecs:           newCodecRegistry(),
+	}
+}
+
+// Engine der Funktionen Request und Response, liest die globale Konfiguration
+func defaultEngine() *Engine {
+	return &Engine{
+		Logger:           Logger,
+		LogAllRequests:   LogAllRequests,
+		ValidatorTagName: ValidatorTagName,
+		AuthRealm:        AuthRealm,
+		ProblemDetails:   ProblemDetails,
+		ProblemTypeBase:  ProblemTypeBase,
+		MaxBodySize:      MaxBodySize,
+		LogBodyPrefix:    LogBodyPrefix,
+		StrictDecoding:   StrictDecoding,
+		NewLogID:         newLogID,
+		validator:        Validator(),
+		codecs:           codecs,
+	}
+}
+
+// Konfiguriere neues request Objekt
+func (e *Engine) Request(r *http.Request) *request {
+	return newRequest(e, r)
+}
+
+// Konfiguriere neues response Objekt
+func (e *Engine) Response(w http.ResponseWriter, r *http.Request) *response {
+	return newResponse(e, w, r)
+}
+
+// Liefert den Validator der Engine.
+// Eigene Regeln, Struct Validierungen und Aliase sollten beim Start registriert werden.
+func (e *Engine) Validator() *validator.Validate {
+	e.validatorOnce.Do(func() {
+		if e.validator == nil {
+			tagName := e.ValidatorTagName
+			if tagName == "" {
+				tagName = ValidatorTagName
+			}
+
+			e.validator = newValidator(tagName)
+		}
+	})
+
+	return e.validator
+}
+
+// Prüft ob alle Validierungs Tags der übergebenen Models bekannt sind
+func (e *Engine) CheckModels(models ...interface{}) error {
+	return checkModels(e.Validator(), models...)
+}
+
+func (e *Engine) logger() *logrus.Logger {
+	if e.Logger == nil {
+		return Logger
+	}
+
+	return e.Logger
+}
+
+func (e *Engine) maxBodySize() int64 {
+	if e.MaxBodySize == 0 {
+		return MaxBodySize
+	}
+
+	return e.MaxBodySize
+}
+
+func (e *Engine) logBodyPrefix() int {
+	if e.LogBodyPrefix == 0 {
+		return LogBodyPrefix
+	}
+
+	return e.LogBodyPrefix
+}
+
+func (e *Engine) authRealm() string {
+	if e.AuthRealm == "" {
+		return AuthRealm
+	}
+
+	return e.AuthRealm
+}
+
+func (e *Engine) logID() (string, error) {
+	if e.NewLogID == nil {
+		return newLogID()
+	}
+
+	return e.NewLogID()
+}
@@ -0,0 +1,220 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+func Test_EngineIndependentLoggers(t *testing.T) {
+	tc := []struct {
+		Body string
+	}{
+		{Body: "engine a"},
+		{Body: "engine b"},
+	}
+
+	// Run test
+	for _, v := range tc {
+		v := v
+		t.Run(v.Body, func(t *testing.T) {
+			t.Parallel()
+
+			logger, mock := test.NewNullLogger()
+			e := NewEngine()
+			e.Logger = logger
+			e.LogAllRequests = true
+
+			req := NewRequest(t, "POST", "/", bytes.NewBufferString(v.Body))
+			if err := e.Request(req).Process(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(mock.Entries) != 1 || mock.LastEntry().Data["body"] != v.Body {
+				t.Fatalf("Expected one entry with body %v was %v", v.Body, mock.Entries)
+			}
+		})
+	}
+}
+
+func Test_EngineResponse(t *testing.T) {
+	tc := struct {
+		ExpectedStatus int
+		ExpectedHeader string
+		ExpectedBody   string
+	}{
+		ExpectedStatus: http.StatusUnauthorized,
+		ExpectedHeader: `Basic realm="Monsters"`,
+		ExpectedBody: `
+		{
+			"code": "unauthorized",
+			"detail": "Unauthorized user",
+			"id": "log-1",
+			"instance": "/",
+			"status": 401,
+			"title": "Unauthorized",
+			"type": "https://example.com/problems/unauthorized"
+		}
+		`,
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		e := NewEngine()
+		e.Logger = logger
+		e.AuthRealm = "Monsters"
+		e.ProblemDetails = true
+		e.ProblemTypeBase = "https://example.com/problems/"
+		e.DefaultAuth = func(string, string) (bool, error) {
+			return false, nil
+		}
+		e.NewLogID = func() (string, error) {
+			return "log-1", nil
+		}
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("Deadpool", ":)")
+		resp := httptest.NewRecorder()
+
+		err := e.Request(req).Process()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+
+		e.Response(resp, req).Error(err)
+
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, resp.Code)
+		}
+
+		if h := resp.Header().Get("WWW-Authenticate"); h != tc.ExpectedHeader {
+			t.Fatalf("Expected %v was %v", tc.ExpectedHeader, h)
+		}
+
+		EqualJSONBody(t, tc.ExpectedBody, resp.Body)
+	}
+}
+
+func Test_EngineFormatError(t *testing.T) {
+	tc := struct {
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		ExpectedStatus: http.StatusNotFound,
+		ExpectedBody: `
+		{
+			"error": {
+				"code": "not_found",
+				"message": "Monster not found",
+				"path": "/v0/monster/1",
+				"status": 404,
+				"trace": "log-1"
+			}
+		}
+		`,
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		e := NewEngine()
+		e.Logger = logger
+		e.ProblemDetails = true
+		e.NewLogID = func() (string, error) {
+			return "log-1", nil
+		}
+		e.FormatError = func(r *http.Request, err Error, status int, id string) interface{} {
+			var code string
+			if s, ok := err.(StatusError); ok {
+				code = s.Code()
+			}
+
+			return map[string]interface{}{
+				"error": map[string]interface{}{
+					"code":    code,
+					"message": err.Message(),
+					"status":  status,
+					"trace":   id,
+					"path":    r.URL.Path,
+				},
+			}
+		}
+
+		req := NewRequest(t, "GET", "/v0/monster/1", &bytes.Buffer{})
+		resp := httptest.NewRecorder()
+		e.Response(resp, req).Error(NotFound("Monster not found", nil))
+
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, resp.Code)
+		}
+
+		if ct := resp.Header().Get("Content-Type"); ct == "application/problem+json" {
+			t.Fatalf("Expected no problem content type was %v", ct)
+		}
+
+		EqualJSONBody(t, tc.ExpectedBody, resp.Body)
+	}
+}
+
+func Test_EngineValidator(t *testing.T) {
+	tc := struct {
+		Body     string
+		Expected string
+	}{
+		Body:     `{"value": ""}`,
+		Expected: "Value failed due to must",
+	}
+
+	// Run test
+	{
+		e := NewEngine()
+		e.ValidatorTagName = "check"
+
+		body := struct {
+			Value string `json:"value" check:"must"`
+		}{}
+
+		e.Validator().RegisterAlias("must", "required")
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+		err := e.Request(req).Post(&body).Process()
+		if err == nil || err.Message() != tc.Expected {
+			t.Fatalf("Expected %v was %v", tc.Expected, err)
+		}
+	}
+}
+
+func Test_EngineZeroValue(t *testing.T) {
+	tc := struct {
+		Body           string
+		ExpectedStatus int
+	}{
+		Body:           `{"value": ""}`,
+		ExpectedStatus: http.StatusBadRequest,
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		e := &Engine{}
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+		resp := httptest.NewRecorder()
+
+		var body TestObject
+		err := e.Request(req).Log().Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		e.Response(resp, req).Error(err)
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, resp.Code)
+		}
+	}
+}
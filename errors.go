@@ -0,0 +1,127 @@
+package hrr
+
+import (
+	"net/http"
+)
+
+type (
+	// Optionale Erweiterung von Error um zusätzliche Felder für Problem Details (RFC 7807)
+	ExtensionError interface {
+		Error
+		Extensions() map[string]interface{}
+	}
+)
+
+// Erzeuge neuen Fehler mit HTTP Status und maschinenlesbarem Code
+func NewStatusError(status int, code, message string, err error) requestError {
+	return requestError{
+		message: message,
+		err:     err,
+		status:  status,
+		code:    code,
+	}
+}
+
+// 400 Bad Request
+func BadRequest(message string, err error) requestError {
+	return NewStatusError(http.StatusBadRequest, "bad_request", message, err)
+}
+
+// 401 Unauthorized
+func Unauthorized(message string, err error) requestError {
+	return NewStatusError(http.StatusUnauthorized, "unauthorized", message, err)
+}
+
+// 403 Forbidden
+func Forbidden(message string, err error) requestError {
+	return NewStatusError(http.StatusForbidden, "forbidden", message, err)
+}
+
+// 404 Not Found
+func NotFound(message string, err error) requestError {
+	return NewStatusError(http.StatusNotFound, "not_found", message, err)
+}
+
+// 409 Conflict
+func Conflict(message string, err error) requestError {
+	return NewStatusError(http.StatusConflict, "conflict", message, err)
+}
+
+// 429 Too Many Requests
+func TooManyRequests(message string, err error) requestError {
+	return NewStatusError(http.StatusTooManyRequests, "too_many_requests", message, err)
+}
+
+// 500 Internal Server Error
+func Internal(message string, err error) requestError {
+	return NewStatusError(http.StatusInternalServerError, "internal", message, err)
+}
+
+// HTTP Status des Fehlers, ohne Angabe 400 Bad Request
+func (err requestError) Status() int {
+	if err.status == 0 {
+		return http.StatusBadRequest
+	}
+
+	return err.status
+}
+
+func (err requestError) Code() string {
+	return err.code
+}
+
+// Der ursprüngliche Fehler, für errors.Is und errors.As
+func (err requestError) Unwrap() error {
+	return err.err
+}
+
+func (err requestError) Header() http.Header {
+	return err.header
+}
+
+// Füge einen Header hinzu der mit dem Fehler gesendet wird
+func (err requestError) WithHeader(key, value string) requestError {
+	h := http.Header{}
+	for k, v := range err.header {
+		h[k] = v
+	}
+	h.Add(key, value)
+
+	err.header = h
+
+	return err
+}
+
+func (err requestError) Extensions() map[string]interface{} {
+	return err.extensions
+}
+
+// Füge ein zusätzliches Feld für Problem Details hinzu
+func (err requestError) WithExtension(key string, value interface{}) requestError {
+	e := map[string]interface{}{}
+	for k, v := range err.extensions {
+		e[k] = v
+	}
+	e[key] = value
+
+	err.extensions = e
+
+	return err
+}
+
+// Status, Code und Header eines beliebigen Errors
+func errorStatus(err Error) (int, string, http.Header) {
+	status := http.StatusBadRequest
+	code := ""
+	if e, ok := err.(StatusError); ok {
+		status = e.Status()
+		code = e.Code()
+	}
+
+	var header http.Header
+	if e, ok := err.(HeaderError); ok {
+		header = e.Header()
+	}
+
+	return status, code, header
+}
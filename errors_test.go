@@ -0,0 +1,98 @@
+package hrr
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+func Test_ResponseStatusError(t *testing.T) {
+	tc := []struct {
+		Err            Error
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		{
+			Err:            NotFound("Monster not found", errors.New("no rows")),
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"id":"\w*","message":"Monster not found","code":"not_found"}`,
+		},
+		{
+			Err:            Conflict("Monster exists", errors.New("unique")),
+			ExpectedStatus: http.StatusConflict,
+			ExpectedBody:   `{"id":"\w*","message":"Monster exists","code":"conflict"}`,
+		},
+		{
+			Err:            TooManyRequests("Slow down", nil),
+			ExpectedStatus: http.StatusTooManyRequests,
+			ExpectedBody:   `{"id":"\w*","message":"Slow down","code":"too_many_requests"}`,
+		},
+		{
+			Err:            Internal("Database failure", errors.New("disk full")),
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `{"id":"\w*","message":"Database failure","code":"internal"}`,
+		},
+		{
+			Err:            NewError("Plain error", errors.New("plain")),
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `{"id":"\w*","message":"Plain error"}`,
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		resp := httptest.NewRecorder()
+		Response(resp, req).Error(v.Err)
+
+		if resp.Code != v.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", v.ExpectedStatus, resp.Code)
+		}
+
+		EqualJSONBody(t, v.ExpectedBody, resp.Body)
+	}
+}
+
+func Test_BaseAuthUnauthorized(t *testing.T) {
+	tc := struct {
+		ExpectedStatus int
+		ExpectedHeader string
+	}{
+		ExpectedStatus: http.StatusUnauthorized,
+		ExpectedHeader: `Basic realm="Restricted"`,
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("Deadpool", "wrong")
+		resp := httptest.NewRecorder()
+
+		err := Request(req).BaseAuth(func(string, string) (bool, error) {
+			return false, nil
+		}).Process()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+
+		Response(resp, req).Error(err)
+
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, resp.Code)
+		}
+
+		if h := resp.Header().Get("WWW-Authenticate"); h != tc.ExpectedHeader {
+			t.Fatalf("Expected %v was %v", tc.ExpectedHeader, h)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package hrr
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -12,23 +13,25 @@ import (
 type (
 	DatabaseMethods interface {
 		NewMonster(name string, cuteness int) (Monster, Error)
-		ReadAllMonsters(sorted_by string) ([]Monster, Error)
+		ReadAllMonsters(list ListQuery) ([]Monster, Error)
+		ReadMonsterPage(list ListQuery, limit, offset int) ([]Monster, int64, Error)
 		ReadMonster(id int64) (Monster, Error)
 		UpdateMonster(id int64, change Monster) (Monster, Error)
 		RemoveMonster(id int64) Error
 	}
 
+	// Mit list markierte Felder dürfen in ?sort= und Filtern verwendet werden
 	Monster struct {
-		ID         int64     `json:"id"`
-		Name       string    `json:"name" validate:"required"`
-		Cuteness   int       `json:"cuteness" validate:"requried"`
-		CreateDate time.Time `json:"created_at"`
+		ID         int64     `json:"id" db:"id" list:"sort"`
+		Name       string    `json:"name" db:"name" validate:"required" list:"sort,filter=eq|like"`
+		Cuteness   int       `json:"cuteness" db:"cuteness" validate:"required" list:"sort,filter"`
+		CreateDate time.Time `json:"created_at" db:"created_at" list:"sort,filter=gte|lte"`
 	}
 
 	Skill struct {
 		ID         int64     `json:"id"`
 		Name       string    `json:"name" validate:"required"`
-		Force      int       `json:"force" validate:"requried"`
+		Force      int       `json:"force" validate:"required"`
 		CreateDate time.Time `json:"created_at"`
 	}
 
@@ -75,12 +78,24 @@ func Example_LittleMonstersDefault() {
 	})
 
 	router.GET("/v0/monsters", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		Request(r).Process()
+		// z.B. /v0/monsters?sort=-cuteness,name&name[like]=Fl*&limit=10
+		var list ListQuery
+		var page Pagination
+		err := Request(r).ListQuery(Monster{}, &list).Paginate(&page, PageConfig{MaxLimit: 50}).Process()
+		if err != nil {
+			Response(w, r).Error(err)
+			return
+		}
 
-		v := r.URL.Query()
+		// Sendet items, total und Links auf die nächste und vorherige Seite
+		Response(w, r).Page(&page, func() (Page, Error) {
+			// Die Datenbank liefert nur die angeforderte Seite
+			monsters, total, err := db.ReadMonsterPage(list, page.Limit, page.Offset)
+			if err != nil {
+				return Page{}, err
+			}
 
-		Response(w, r).Data(func() (interface{}, Error) {
-			return db.ReadAllMonsters(v.Get("sorted_by"))
+			return Page{Items: monsters, Total: &total}, nil
 		})
 	})
 
@@ -114,6 +129,39 @@ func Example_LittleMonstersDefault() {
 		})
 	})
 
+	router.PATCH("/v0/monster/:monsterID", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		var id int64
+		var monster Monster
+		var changed []string
+
+		// Lade das aktuelle Monster, der Patch wird darauf angewendet
+		load := func() Error {
+			current, err := db.ReadMonster(id)
+			if err != nil {
+				return err
+			}
+
+			monster = current
+
+			return nil
+		}
+
+		req := Request(r).
+			ParamInt64(p, "monsterID", &id).
+			Patch(&monster, load, &changed).
+			BaseAuth(VerifyUser)
+		if err := req.Process(); err != nil {
+			Response(w, r).Error(err)
+			return
+		}
+
+		Logger.Infof("Monster %v changed %v", id, changed)
+
+		Response(w, r).Data(func() (interface{}, Error) {
+			return db.UpdateMonster(id, monster)
+		})
+	})
+
 	router.DELETE("/v0/monster/:monsterID", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
 		var id int64
 		err := Request(r).ParamInt64(p, "monsterID", &id).Process()
@@ -137,11 +185,17 @@ func Example_LittleMonstersShortcuts() {
 	// Logt jeden Request aufruf
 	LogAllRequests = true
 
+	// Wiederholte POST Requests mit gleichem Idempotency-Key legen kein neues Monster an
+	idempotency := NewMemoryIdempotencyStore()
+
 	router := httprouter.New()
 
 	router.POST("/v0/monster", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		body := Monster{}
-		if err := Request(r).Post(&body).BaseAuth(VerifyUser).Process(); err != nil {
+		err := Request(r).Post(&body).BaseAuth(VerifyUser).
+			Idempotent(IdempotencyConfig{Store: idempotency}).
+			Process()
+		if err != nil {
 			Response(w, r).Error(err)
 			return
 		}
@@ -152,14 +206,14 @@ func Example_LittleMonstersShortcuts() {
 	})
 
 	router.GET("/v0/monsters", func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		if err := Request(r).Process(); err != nil {
+		var list ListQuery
+		if err := Request(r).ListQuery(Monster{}, &list).Process(); err != nil {
 			Response(w, r).Error(err)
 			return
 		}
 
 		Response(w, r).Data(func() (interface{}, Error) {
-			v := r.URL.Query()
-			return db.ReadAllMonsters(v.Get("sorted_by"))
+			return db.ReadAllMonsters(list)
 		})
 	})
 
@@ -170,7 +224,8 @@ func Example_LittleMonstersShortcuts() {
 			return
 		}
 
-		Response(w, r).Data(func() (interface{}, Error) {
+		// Clients erhalten 304 Not Modified solange sich das Monster nicht ändert
+		Response(w, r).ETag().Data(func() (interface{}, Error) {
 			return db.ReadMonster(id)
 		})
 	})
@@ -179,7 +234,22 @@ func Example_LittleMonstersShortcuts() {
 		var id int64
 		var monster Monster
 
-		req := Request(r).Put(&monster, p, "monsterID", &id).BaseAuth(VerifyUser)
+		// Änderung nur wenn If-Match zum aktuellen Monster passt
+		version := func() (string, time.Time, Error) {
+			current, err := db.ReadMonster(id)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+
+			body, e := JSONCodec.Marshal(current)
+			if e != nil {
+				return "", time.Time{}, Internal("Error cannot encode monster", e)
+			}
+
+			return NewETag(body, false), time.Time{}, nil
+		}
+
+		req := Request(r).Put(&monster, p, "monsterID", &id).Precondition(version).BaseAuth(VerifyUser)
 		if err := req.Process(); err != nil {
 			Response(w, r).Error(err)
 			return
@@ -232,6 +302,31 @@ func Example_LittleMonstersVerifyUser_v2() {
 	})
 }
 
+func Example_littleMonstersPrincipal() {
+	verifyUser := func(user, pass string) (*Identity, error) {
+		ok, err := VerifyUser(user, pass)
+		if !ok || err != nil {
+			return nil, err
+		}
+
+		return &Identity{ID: user, Roles: []string{"keeper"}}, nil
+	}
+
+	router := httprouter.New()
+
+	router.POST("/v0/monster", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+		var monster Monster
+		if err := Request(r).Post(&monster).BaseAuthIdentity(verifyUser).Process(); err != nil {
+			Response(w, r).Error(err)
+			return
+		}
+
+		Logger.Printf("User %v create new monster", Principal(r).ID)
+
+		Response(w, r).OK()
+	})
+}
+
 func (sqlitePool) NewMonster(name string, cuteness int) (Monster, Error) {
 	return Monster{
 		ID:         1,
@@ -241,18 +336,43 @@ func (sqlitePool) NewMonster(name string, cuteness int) (Monster, Error) {
 	}, nil
 }
 
-func (sqlitePool) ReadAllMonsters(sorted_by string) ([]Monster, Error) {
-	return []Monster{
-		{
-			ID:         1,
-			Name:       "X10",
-			Cuteness:   -100,
-			CreateDate: time.Now(),
-		},
-	}, nil
+func (p sqlitePool) ReadAllMonsters(list ListQuery) ([]Monster, Error) {
+	// Spalten und Operatoren stammen aus der Allow-List, Werte sind Platzhalter
+	query, args := list.SQL("SELECT id, name, cuteness, created_at FROM monsters")
+
+	var monsters []Monster
+	if err := p.db.Select(&monsters, p.db.Rebind(query), args...); err != nil {
+		return nil, Internal("Error cannot read monsters", err)
+	}
+
+	return monsters, nil
+}
+
+func (p sqlitePool) ReadMonsterPage(list ListQuery, limit, offset int) ([]Monster, int64, Error) {
+	where, args := list.Where()
+
+	var total int64
+	if err := p.db.Get(&total, p.db.Rebind("SELECT COUNT(*) FROM monsters "+where), args...); err != nil {
+		return nil, 0, Internal("Error cannot count monsters", err)
+	}
+
+	query, args := list.SQL("SELECT id, name, cuteness, created_at FROM monsters")
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	var monsters []Monster
+	if err := p.db.Select(&monsters, p.db.Rebind(query), args...); err != nil {
+		return nil, 0, Internal("Error cannot read monsters", err)
+	}
+
+	return monsters, total, nil
 }
 
 func (sqlitePool) ReadMonster(id int64) (Monster, Error) {
+	if id <= 0 {
+		return Monster{}, NotFound("Monster not found", fmt.Errorf("No monster with id %v", id))
+	}
+
 	return Monster{
 		ID:         id,
 		Name:       "Fluffy",
@@ -0,0 +1,443 @@
+package hrr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type (
+	// Konfiguration für Idempotent
+	IdempotencyConfig struct {
+		Store IdempotencyStore
+		// Header mit dem Schlüssel, Standard ist Idempotency-Key
+		Header string
+		// Requests ohne Schlüssel werden mit 400 Bad Request abgelehnt
+		Required bool
+		// Wie lange eine Antwort gespeichert wird, Standard ist 24 Stunden
+		TTL time.Duration
+		// Nach dieser Zeit gilt ein nicht beendeter Request als abgebrochen,
+		// Standard ist eine Minute
+		LockTimeout time.Duration
+	}
+
+	// Gespeicherte Antwort eines Requests
+	IdempotencyRecord struct {
+		Key         string
+		Fingerprint string
+		Completed   bool
+		Status      int
+		Header      http.Header
+		Body        []byte
+		// Bis wann ein laufender Request den Schlüssel hält
+		LockedUntil time.Time
+		// Bis wann eine beendete Antwort gespeichert bleibt
+		ExpiresAt time.Time
+	}
+
+	// Speicher für Idempotency Records, alle Methoden müssen atomar sein
+	IdempotencyStore interface {
+		// Legt record an wenn der Schlüssel frei ist. Existiert ein gültiger Eintrag
+		// wird dieser geliefert und created ist false. Abgelaufene Einträge werden ersetzt.
+		Begin(record IdempotencyRecord) (existing *IdempotencyRecord, created bool, err error)
+		// Speichert die Antwort eines laufenden Requests
+		Complete(record IdempotencyRecord) error
+		// Gibt den Schlüssel frei, z.B. nach einem Server Fehler
+		Release(key string) error
+	}
+
+	// Idempotency Records im Speicher
+	MemoryIdempotencyStore struct {
+		mu      sync.Mutex
+		records map[string]IdempotencyRecord
+	}
+
+	// Idempotency Records in einer SQL Tabelle, siehe SQLIdempotencyStoreSchema
+	SQLIdempotencyStore struct {
+		db    *sqlx.DB
+		table string
+	}
+
+	sqlIdempotencyRecord struct {
+		Key         string    `db:"idempotency_key"`
+		Fingerprint string    `db:"fingerprint"`
+		Completed   bool      `db:"completed"`
+		Status      int       `db:"status"`
+		Header      []byte    `db:"header"`
+		Body        []byte    `db:"body"`
+		LockedUntil time.Time `db:"locked_until"`
+		ExpiresAt   time.Time `db:"expires_at"`
+	}
+
+	// Laufender Request, liegt im Context bis die Antwort gesendet wurde
+	idempotencyEntry struct {
+		store  IdempotencyStore
+		record IdempotencyRecord
+		ttl    time.Duration
+		once   sync.Once
+	}
+
+	idempotencyKey struct{}
+
+	// Fehler von Process wenn eine gespeicherte Antwort existiert,
+	// Response.Error sendet dann die gespeicherte Antwort
+	idempotencyReplay struct {
+		record *IdempotencyRecord
+	}
+
+	// Merkt sich Status, Header und Body einer Antwort
+	recordingWriter struct {
+		http.ResponseWriter
+		status int
+		header http.Header
+		body   bytes.Buffer
+	}
+)
+
+// Tabelle für SQLIdempotencyStore, %v wird durch den Tabellennamen ersetzt
+const SQLIdempotencyStoreSchema = `CREATE TABLE IF NOT EXISTS %v (
+	idempotency_key TEXT PRIMARY KEY,
+	fingerprint     TEXT NOT NULL,
+	completed       BOOLEAN NOT NULL DEFAULT FALSE,
+	status          INTEGER NOT NULL DEFAULT 0,
+	header          BLOB,
+	body            BLOB,
+	locked_until    TIMESTAMP NOT NULL,
+	expires_at      TIMESTAMP NOT NULL
+)`
+
+var (
+	errMissingIdempotencyKey = errors.New("Missing idempotency key")
+	errIdempotencyMismatch   = errors.New("Idempotency key reused with different payload")
+	errIdempotencyInFlight   = errors.New("Idempotency key in use")
+)
+
+// Speichere die erste Antwort zu einem Idempotency-Key und sende sie bei
+// wiederholten Requests erneut. Der Schlüssel gilt pro angemeldetem Aufrufer.
+// Bei einer Wiederholung liefert Process einen Fehler, Response.Error sendet dann
+// die gespeicherte Antwort. Ein anderer Body mit gleichem Schlüssel liefert
+// 422 Unprocessable Entity, ein noch laufender Request 409 Conflict.
+// Antworten mit Server Fehlern werden nicht gespeichert.
+func (r *request) Idempotent(config IdempotencyConfig) *request {
+	r.idempotency = &config
+	return r
+}
+
+func (r *request) idempotent() Error {
+	config := r.idempotency
+
+	header := config.Header
+	if header == "" {
+		header = "Idempotency-Key"
+	}
+
+	key := r.request.Header.Get(header)
+	if key == "" {
+		if config.Required {
+			return BadRequest("Error missing header "+header, errMissingIdempotencyKey)
+		}
+
+		return nil
+	}
+
+	if id := Principal(r.request); id != nil {
+		key = id.ID + ":" + key
+	}
+
+	ttl := config.TTL
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	lock := config.LockTimeout
+	if lock == 0 {
+		lock = time.Minute
+	}
+
+	now := time.Now()
+	record := IdempotencyRecord{
+		Key:         key,
+		Fingerprint: r.fingerprint(),
+		LockedUntil: now.Add(lock),
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	existing, created, err := config.Store.Begin(record)
+	if err != nil {
+		return Internal("Error cannot check idempotency key", err)
+	}
+
+	if !created {
+		if existing.Fingerprint != record.Fingerprint {
+			return NewStatusError(http.StatusUnprocessableEntity, "idempotency_key_reused",
+				"Error idempotency key reused with different payload", errIdempotencyMismatch)
+		}
+
+		if !existing.Completed {
+			return NewStatusError(http.StatusConflict, "idempotency_key_in_use",
+				"Error request with same idempotency key in progress", errIdempotencyInFlight)
+		}
+
+		return idempotencyReplay{record: existing}
+	}
+
+	entry := &idempotencyEntry{store: config.Store, record: record, ttl: ttl}
+	r.setContext(context.WithValue(r.request.Context(), idempotencyKey{}, entry))
+
+	return nil
+}
+
+// Hash über Methode, Pfad und Body
+func (r *request) fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%v %v\n", r.request.Method, r.request.URL.RequestURI())
+	h.Write(r.body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func idempotencyFromContext(ctx context.Context) *idempotencyEntry {
+	entry, _ := ctx.Value(idempotencyKey{}).(*idempotencyEntry)
+	return entry
+}
+
+func (e idempotencyReplay) Message() string {
+	return "Replayed idempotent request"
+}
+
+func (e idempotencyReplay) Error() string {
+	return "Replayed response for idempotency key " + e.record.Key
+}
+
+// Sende die gespeicherte Antwort
+func (e idempotencyReplay) write(w http.ResponseWriter) {
+	for k, v := range e.record.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(e.record.Status)
+	w.Write(e.record.Body)
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.header = w.Header().Clone()
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.body.Write(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Speichere die gesendete Antwort, bei Server Fehlern wird der Schlüssel freigegeben
+func (e *idempotencyEntry) finish(w *recordingWriter) error {
+	var err error
+	e.once.Do(func() {
+		status := w.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		if status >= 500 {
+			err = e.store.Release(e.record.Key)
+			return
+		}
+
+		record := e.record
+		record.Completed = true
+		record.Status = status
+		record.Header = w.header
+		record.Body = w.body.Bytes()
+		record.ExpiresAt = time.Now().Add(e.ttl)
+
+		err = e.store.Complete(record)
+	})
+
+	return err
+}
+
+// Ein Eintrag ist abgelaufen wenn die Antwort zu alt ist oder der Request abgebrochen wurde
+func (record IdempotencyRecord) expired(now time.Time) bool {
+	if record.Completed {
+		return now.After(record.ExpiresAt)
+	}
+
+	return now.After(record.LockedUntil)
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: map[string]IdempotencyRecord{}}
+}
+
+func (s *MemoryIdempotencyStore) Begin(record IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range s.records {
+		if v.expired(now) {
+			delete(s.records, k)
+		}
+	}
+
+	if existing, ok := s.records[record.Key]; ok {
+		return &existing, false, nil
+	}
+
+	s.records[record.Key] = record
+
+	return nil, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.Key] = record
+
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+// Erzeuge SQLIdempotencyStore und lege die Tabelle an falls sie fehlt
+func NewSQLIdempotencyStore(db *sqlx.DB, table string) (*SQLIdempotencyStore, error) {
+	_, err := db.Exec(fmt.Sprintf(SQLIdempotencyStoreSchema, table))
+	if err != nil {
+		return nil, err
+	}
+
+	return &SQLIdempotencyStore{db: db, table: table}, nil
+}
+
+func (s *SQLIdempotencyStore) Begin(record IdempotencyRecord) (*IdempotencyRecord, bool, error) {
+	row, err := newSQLIdempotencyRecord(record)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Zeiten in UTC, damit sie auch als Text vergleichbar sind
+	now := time.Now().UTC()
+
+	// Abgelaufene Einträge entfernen, danach gewinnt genau ein INSERT
+	q := s.db.Rebind(fmt.Sprintf(`DELETE FROM %v WHERE idempotency_key = ?
+		AND ((completed AND expires_at < ?) OR (NOT completed AND locked_until < ?))`, s.table))
+	if _, err := s.db.Exec(q, record.Key, now, now); err != nil {
+		return nil, false, err
+	}
+
+	q = fmt.Sprintf(`INSERT INTO %v (idempotency_key, fingerprint, completed, status, header, body, locked_until, expires_at)
+		VALUES (:idempotency_key, :fingerprint, :completed, :status, :header, :body, :locked_until, :expires_at)
+		ON CONFLICT (idempotency_key) DO NOTHING`, s.table)
+	result, err := s.db.NamedExec(q, row)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 1 {
+		return nil, err == nil, err
+	}
+
+	var existing sqlIdempotencyRecord
+	q = s.db.Rebind(fmt.Sprintf(`SELECT idempotency_key, fingerprint, completed, status, header, body, locked_until, expires_at
+		FROM %v WHERE idempotency_key = ?`, s.table))
+	err = s.db.Get(&existing, q, record.Key)
+	if err == sql.ErrNoRows {
+		// Gerade freigegeben, neuer Versuch
+		return s.Begin(record)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	found, err := existing.record()
+	if err != nil {
+		return nil, false, err
+	}
+
+	return found, false, nil
+}
+
+func (s *SQLIdempotencyStore) Complete(record IdempotencyRecord) error {
+	row, err := newSQLIdempotencyRecord(record)
+	if err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf(`UPDATE %v SET completed = :completed, status = :status, header = :header,
+		body = :body, expires_at = :expires_at WHERE idempotency_key = :idempotency_key`, s.table)
+	_, err = s.db.NamedExec(q, row)
+
+	return err
+}
+
+func (s *SQLIdempotencyStore) Release(key string) error {
+	q := s.db.Rebind(fmt.Sprintf(`DELETE FROM %v WHERE idempotency_key = ?`, s.table))
+	_, err := s.db.Exec(q, key)
+
+	return err
+}
+
+func newSQLIdempotencyRecord(record IdempotencyRecord) (sqlIdempotencyRecord, error) {
+	header, err := json.Marshal(record.Header)
+	if err != nil {
+		return sqlIdempotencyRecord{}, err
+	}
+
+	return sqlIdempotencyRecord{
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		Completed:   record.Completed,
+		Status:      record.Status,
+		Header:      header,
+		Body:        record.Body,
+		LockedUntil: record.LockedUntil.UTC(),
+		ExpiresAt:   record.ExpiresAt.UTC(),
+	}, nil
+}
+
+func (row sqlIdempotencyRecord) record() (*IdempotencyRecord, error) {
+	var header http.Header
+	if len(row.Header) > 0 {
+		if err := json.Unmarshal(row.Header, &header); err != nil {
+			return nil, err
+		}
+	}
+
+	return &IdempotencyRecord{
+		Key:         row.Key,
+		Fingerprint: row.Fingerprint,
+		Completed:   row.Completed,
+		Status:      row.Status,
+		Header:      header,
+		Body:        row.Body,
+		LockedUntil: row.LockedUntil,
+		ExpiresAt:   row.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,264 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+	"github.com/jmoiron/sqlx"
+)
+
+type idempotentMonster struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Cuteness int    `json:"cuteness"`
+}
+
+func Test_Idempotent(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	Logger = logger
+
+	store := NewMemoryIdempotencyStore()
+	created := 0
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var body idempotentMonster
+		err := Request(r).Post(&body).Idempotent(IdempotencyConfig{Store: store}).Process()
+		if err != nil {
+			Response(w, r).Error(err)
+			return
+		}
+
+		Response(w, r).Post(func() (interface{}, Error) {
+			if body.Name == "Broken" {
+				return nil, Internal("Database failure", nil)
+			}
+
+			created++
+			body.ID = int64(created)
+			return body, nil
+		})
+	}
+
+	tc := []struct {
+		Key              string
+		Body             string
+		ExpectedStatus   int
+		ExpectedBody     string
+		ExpectedReplayed bool
+		ExpectedCreated  int
+	}{
+		{
+			Key:             "a",
+			Body:            `{"name":"Gruffalo","cuteness":1}`,
+			ExpectedStatus:  http.StatusCreated,
+			ExpectedBody:    `{"id":1,"name":"Gruffalo","cuteness":1}`,
+			ExpectedCreated: 1,
+		},
+		{
+			Key:              "a",
+			Body:             `{"name":"Gruffalo","cuteness":1}`,
+			ExpectedStatus:   http.StatusCreated,
+			ExpectedBody:     `{"id":1,"name":"Gruffalo","cuteness":1}`,
+			ExpectedReplayed: true,
+			ExpectedCreated:  1,
+		},
+		{
+			Key:             "a",
+			Body:            `{"name":"Gruffalo","cuteness":2}`,
+			ExpectedStatus:  http.StatusUnprocessableEntity,
+			ExpectedCreated: 1,
+		},
+		// Ohne Schlüssel wird immer ausgeführt
+		{
+			Body:            `{"name":"Gruffalo","cuteness":1}`,
+			ExpectedStatus:  http.StatusCreated,
+			ExpectedBody:    `{"id":2,"name":"Gruffalo","cuteness":1}`,
+			ExpectedCreated: 2,
+		},
+		// Server Fehler werden nicht gespeichert
+		{
+			Key:             "b",
+			Body:            `{"name":"Broken","cuteness":1}`,
+			ExpectedStatus:  http.StatusInternalServerError,
+			ExpectedCreated: 2,
+		},
+		{
+			Key:             "b",
+			Body:            `{"name":"Broken","cuteness":1}`,
+			ExpectedStatus:  http.StatusInternalServerError,
+			ExpectedCreated: 2,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "POST", "/v0/monster", bytes.NewBufferString(v.Body))
+		if v.Key != "" {
+			req.Header.Set("Idempotency-Key", v.Key)
+		}
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+
+		if resp.Code != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v %v", i, v.ExpectedStatus, resp.Code, resp.Body)
+		}
+
+		if v.ExpectedBody != "" {
+			EqualJSONBody(t, v.ExpectedBody, resp.Body)
+		}
+
+		if replayed := resp.Header().Get("Idempotent-Replayed") == "true"; replayed != v.ExpectedReplayed {
+			t.Fatalf("%v: Expected replayed %v was %v", i, v.ExpectedReplayed, replayed)
+		}
+
+		if v.ExpectedReplayed && resp.Header().Get("Content-Type") != "application/json" {
+			t.Fatalf("%v: Expected stored Content-Type was %v", i, resp.Header())
+		}
+
+		if created != v.ExpectedCreated {
+			t.Fatalf("%v: Expected %v created was %v", i, v.ExpectedCreated, created)
+		}
+	}
+}
+
+func Test_IdempotentInFlight(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	config := IdempotencyConfig{Store: store, Required: true}
+
+	tc := []struct {
+		Key            string
+		ExpectedStatus int
+	}{
+		{Key: "a"},
+		{Key: "a", ExpectedStatus: http.StatusConflict},
+		{Key: "b"},
+		{ExpectedStatus: http.StatusBadRequest},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(`{}`))
+		req.SetBasicAuth("krake", "tinte")
+		if v.Key != "" {
+			req.Header.Set("Idempotency-Key", v.Key)
+		}
+
+		err := Request(req).
+			BaseAuthIdentity(func(user, password string) (*Identity, error) {
+				if user != "krake" || password != "tinte" {
+					return nil, nil
+				}
+
+				return &Identity{ID: user}, nil
+			}).
+			Idempotent(config).
+			Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+
+	if _, ok := store.records["krake:a"]; !ok {
+		t.Fatalf("Expected key per principal was %v", store.records)
+	}
+}
+
+func Test_IdempotentWithoutAuth(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+
+	// Run test
+	{
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(`{}`))
+		req.SetBasicAuth("krake", "")
+		req.Header.Set("Idempotency-Key", "a")
+
+		err := Request(req).Idempotent(IdempotencyConfig{Store: store}).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Ohne Anmeldung darf der Authorization Header keinen fremden Namensraum wählen
+		if _, ok := store.records["krake:a"]; ok {
+			t.Fatalf("Expected anonymous key was %v", store.records)
+		}
+
+		if _, ok := store.records["a"]; !ok {
+			t.Fatalf("Expected anonymous key was %v", store.records)
+		}
+	}
+}
+
+func Test_SQLIdempotencyStore(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	store, err := NewSQLIdempotencyStore(db, "idempotency")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	record := IdempotencyRecord{
+		Key:         "krake:a",
+		Fingerprint: "abc",
+		LockedUntil: now.Add(time.Minute),
+		ExpiresAt:   now.Add(time.Hour),
+	}
+
+	// Run test
+	{
+		if _, created, err := store.Begin(record); err != nil || !created {
+			t.Fatalf("Expected created was (%v, %v)", created, err)
+		}
+
+		existing, created, err := store.Begin(record)
+		if err != nil || created || existing.Completed || existing.Fingerprint != "abc" {
+			t.Fatalf("Expected in flight record was (%v, %v, %v)", existing, created, err)
+		}
+
+		done := record
+		done.Completed = true
+		done.Status = http.StatusCreated
+		done.Header = http.Header{"Content-Type": {"application/json"}}
+		done.Body = []byte(`{"id":1}`)
+		if err := store.Complete(done); err != nil {
+			t.Fatal(err)
+		}
+
+		existing, created, err = store.Begin(record)
+		if err != nil || created || !existing.Completed ||
+			existing.Status != http.StatusCreated ||
+			existing.Header.Get("Content-Type") != "application/json" ||
+			string(existing.Body) != `{"id":1}` {
+			t.Fatalf("Expected completed record was (%v, %v, %v)", existing, created, err)
+		}
+
+		if err := store.Release(record.Key); err != nil {
+			t.Fatal(err)
+		}
+
+		// Abgebrochener Request wird ersetzt
+		stale := record
+		stale.LockedUntil = now.Add(-time.Minute)
+		if _, created, err := store.Begin(stale); err != nil || !created {
+			t.Fatalf("Expected created was (%v, %v)", created, err)
+		}
+
+		if _, created, err := store.Begin(record); err != nil || !created {
+			t.Fatalf("Expected stale record to be replaced was (%v, %v)", created, err)
+		}
+	}
+}
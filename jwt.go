@@ -0,0 +1,215 @@
+package hrr
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type (
+	// Claims eines verifizierten JWT
+	Claims map[string]interface{}
+
+	// Konfiguration für JWTVerifier
+	JWTConfig struct {
+		// Erlaubte Algorithmen, ohne Angabe HS256, RS256 und ES256
+		Algorithms []string
+		// Schlüssel für HS256
+		HMACKey []byte
+		// Schlüssel für RS256 (*rsa.PublicKey) oder ES256 (*ecdsa.PublicKey)
+		PublicKey crypto.PublicKey
+		// Lokale JWKS Datei, Schlüssel werden über die kid des Tokens gefunden
+		JWKSFile string
+		Issuer   string
+		Audience string
+		// Erlaubte Abweichung der Uhren bei exp und nbf
+		ClockSkew time.Duration
+	}
+
+	// Prüft Signatur, exp, nbf, aud und iss eines JWT.
+	// Tokens ohne exp werden abgelehnt.
+	JWTVerifier struct {
+		config JWTConfig
+		keys   map[string]crypto.PublicKey
+		parser *jwt.Parser
+	}
+
+	jsonWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+// Erzeuge neuen JWTVerifier, eine angegebene JWKS Datei wird sofort gelesen
+func NewJWTVerifier(config JWTConfig) (*JWTVerifier, error) {
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"HS256", "RS256", "ES256"}
+	}
+
+	v := &JWTVerifier{
+		config: config,
+		keys:   map[string]crypto.PublicKey{},
+	}
+
+	if config.JWKSFile != "" {
+		keys, err := readJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = keys
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(config.Algorithms),
+		jwt.WithLeeway(config.ClockSkew),
+		jwt.WithExpirationRequired(),
+	}
+	if config.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(config.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+
+	return v, nil
+}
+
+// Prüfe einen Token und liefere seine Claims
+func (v *JWTVerifier) Verify(token string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, v.key)
+	if err != nil {
+		return nil, err
+	}
+
+	return Claims(claims), nil
+}
+
+// Aktiviere Bearer Auth mit JWT, die Claims des Tokens werden in claims abgelegt.
+// Der Aufrufer (sub, roles, scope) ist zusätzlich über Principal erreichbar.
+func (r *request) JWTAuth(v *JWTVerifier, claims *Claims) *request {
+	return r.BearerAuthIdentity(func(token string) (*Identity, error) {
+		c, err := v.Verify(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if claims != nil {
+			*claims = c
+		}
+
+		return identityFromClaims(c), nil
+	})
+}
+
+func (v *JWTVerifier) key(t *jwt.Token) (interface{}, error) {
+	switch t.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if len(v.config.HMACKey) == 0 {
+			return nil, errors.New("No HMAC key configured")
+		}
+		return v.config.HMACKey, nil
+	}
+
+	if kid, ok := t.Header["kid"].(string); ok {
+		if k, ok := v.keys[kid]; ok {
+			return k, nil
+		}
+	}
+
+	if v.config.PublicKey != nil {
+		return v.config.PublicKey, nil
+	}
+
+	return nil, fmt.Errorf("No key found for token %v", t.Header["kid"])
+}
+
+// Liest die RSA und EC Schlüssel einer JWKS Datei
+func readJWKS(path string) (map[string]crypto.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %v: %v", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64BigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64BigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("Not supported curve %v", k.Crv)
+		}
+		x, err := base64BigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64BigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("Not supported key type %v", k.Kty)
+}
+
+func base64BigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,189 @@
+package hrr
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_JWTAuthHS256(t *testing.T) {
+	key := []byte("monster secret")
+	now := time.Now()
+
+	tc := []struct {
+		Claims        jwt.MapClaims
+		Key           []byte
+		ExpectedError bool
+	}{
+		{
+			Claims: jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api", "exp": now.Add(time.Minute).Unix()},
+			Key:    key,
+		},
+		{
+			// Abgelaufen, aber innerhalb der erlaubten Abweichung
+			Claims: jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api", "exp": now.Add(-10 * time.Second).Unix()},
+			Key:    key,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api", "exp": now.Add(-time.Hour).Unix()},
+			Key:           key,
+			ExpectedError: true,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api", "exp": now.Add(time.Minute).Unix(), "nbf": now.Add(time.Hour).Unix()},
+			Key:           key,
+			ExpectedError: true,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "other", "exp": now.Add(time.Minute).Unix()},
+			Key:           key,
+			ExpectedError: true,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "evil", "aud": "api", "exp": now.Add(time.Minute).Unix()},
+			Key:           key,
+			ExpectedError: true,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api"},
+			Key:           key,
+			ExpectedError: true,
+		},
+		{
+			Claims:        jwt.MapClaims{"sub": "fluffy", "iss": "monsters", "aud": "api", "exp": now.Add(time.Minute).Unix()},
+			Key:           []byte("wrong"),
+			ExpectedError: true,
+		},
+	}
+
+	v, err := NewJWTVerifier(JWTConfig{
+		Algorithms: []string{"HS256"},
+		HMACKey:    key,
+		Issuer:     "monsters",
+		Audience:   "api",
+		ClockSkew:  30 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run test
+	for i, c := range tc {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c.Claims).SignedString(c.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		var claims Claims
+		e := Request(req).JWTAuth(v, &claims).Process()
+		if c.ExpectedError {
+			if e == nil {
+				t.Fatalf("%v: Expected error", i)
+			}
+			continue
+		}
+
+		if e != nil {
+			t.Fatalf("%v: %v", i, e)
+		}
+
+		if claims["sub"] != "fluffy" {
+			t.Fatalf("%v: Expected fluffy was %v", i, claims["sub"])
+		}
+	}
+}
+
+func Test_JWTAuthJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := func(i *big.Int) string {
+		return base64.RawURLEncoding.EncodeToString(i.Bytes())
+	}
+
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "n": "%v", "e": "%v"},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": "%v", "y": "%v"}
+	]}`, b64(rsaKey.N), b64(big.NewInt(int64(rsaKey.E))), b64(ecKey.X), b64(ecKey.Y))
+
+	f, err := ioutil.TempFile("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(jwks)
+	f.Close()
+
+	v, err := NewJWTVerifier(JWTConfig{
+		Algorithms: []string{"RS256", "ES256"},
+		JWKSFile:   f.Name(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub": "fluffy",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = kid
+
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	}
+
+	tc := []struct {
+		Token         string
+		ExpectedError bool
+	}{
+		{Token: sign(jwt.SigningMethodRS256, "rsa-1", rsaKey)},
+		{Token: sign(jwt.SigningMethodES256, "ec-1", ecKey)},
+		{Token: sign(jwt.SigningMethodES256, "unknown", ecKey), ExpectedError: true},
+		{Token: sign(jwt.SigningMethodHS256, "rsa-1", []byte("x")), ExpectedError: true},
+	}
+
+	// Run test
+	for i, c := range tc {
+		claims, err := v.Verify(c.Token)
+		if c.ExpectedError {
+			if err == nil {
+				t.Fatalf("%v: Expected error", i)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if claims["sub"] != "fluffy" {
+			t.Fatalf("%v: Expected fluffy was %v", i, claims["sub"])
+		}
+	}
+}
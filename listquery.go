@@ -0,0 +1,391 @@
+package hrr
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Vergleichsoperator eines Filters
+	FilterOp string
+
+	// Sortierung nach einem Feld
+	SortField struct {
+		Field  string
+		Column string
+		Desc   bool
+	}
+
+	// Ein Filter der Form feld[op]=wert. Value hat den Typ des Struct Feldes,
+	// bei in ist Value ein []interface{}.
+	Filter struct {
+		Field  string
+		Column string
+		Op     FilterOp
+		Value  interface{}
+	}
+
+	// Gelesene Sortierung und Filter eines Requests
+	ListQuery struct {
+		Sort    []SortField
+		Filters []Filter
+	}
+
+	// Erlaubtes Feld aus dem list Tag eines Models
+	listField struct {
+		name     string
+		column   string
+		typ      reflect.Type
+		sortable bool
+		ops      []FilterOp
+	}
+
+	listRequest struct {
+		model interface{}
+		dst   *ListQuery
+	}
+)
+
+const (
+	OpEq   FilterOp = "eq"
+	OpNe   FilterOp = "ne"
+	OpGt   FilterOp = "gt"
+	OpGte  FilterOp = "gte"
+	OpLt   FilterOp = "lt"
+	OpLte  FilterOp = "lte"
+	OpLike FilterOp = "like"
+	OpIn   FilterOp = "in"
+)
+
+var (
+	sqlOperators = map[FilterOp]string{
+		OpEq:  "=",
+		OpNe:  "<>",
+		OpGt:  ">",
+		OpGte: ">=",
+		OpLt:  "<",
+		OpLte: "<=",
+	}
+
+	// Erlaubte Felder nach Model Typ
+	listFields sync.Map
+
+	errUnknownListField = errors.New("Unknown field")
+	errListOp           = errors.New("Operator not allowed")
+)
+
+// Lese sort und Filter aus der Query. Erlaubt sind nur Felder von model mit
+// list Tag, z.B. `json:"cuteness" db:"cuteness" list:"sort,filter=eq|gte|lte"`.
+// Ohne Angabe der Operatoren sind alle zum Typ passenden Operatoren erlaubt.
+// Der Name des Feldes stammt aus dem json Tag, die Spalte aus dem db Tag.
+//
+//	?sort=-cuteness,name&cuteness[gte]=10&name[like]=Fl*
+func (r *request) ListQuery(model interface{}, dst *ListQuery) *request {
+	r.listQuery = &listRequest{model: model, dst: dst}
+	return r
+}
+
+func (l listRequest) parse(query url.Values) Error {
+	fields, err := modelListFields(l.model)
+	if err != nil {
+		return Internal("Error invalid list model", err)
+	}
+
+	*l.dst = ListQuery{}
+
+	if s := query.Get("sort"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			// Ein + kommt nach dem Dekodieren der Query als Leerzeichen an
+			name = strings.TrimSpace(name)
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(strings.TrimPrefix(name, "-"), "+")
+
+			f, ok := fields[name]
+			if !ok || !f.sortable {
+				return NewError(fmt.Sprintf("Error cannot sort by %v", name), errUnknownListField).
+					WithExtension("field", name)
+			}
+
+			l.dst.Sort = append(l.dst.Sort, SortField{Field: f.name, Column: f.column, Desc: desc})
+		}
+	}
+
+	// Feste Reihenfolge für reproduzierbare SQL Statements
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name, op, explicit := parseFilterKey(key)
+		f, ok := fields[name]
+		if !ok || len(f.ops) == 0 {
+			// Andere Query Parameter wie limit werden ignoriert
+			if explicit {
+				return NewError(fmt.Sprintf("Error cannot filter by %v", name), errUnknownListField).
+					WithExtension("field", name)
+			}
+
+			continue
+		}
+
+		if !containsOp(f.ops, op) {
+			return NewError(fmt.Sprintf("Error operator %v not allowed for %v", op, name), errListOp).
+				WithExtension("field", name)
+		}
+
+		for _, raw := range query[key] {
+			value, err := f.parseValue(op, raw)
+			if err != nil {
+				return NewError(fmt.Sprintf("Error cannot parse filter %v", key), err).
+					WithExtension("field", name)
+			}
+
+			l.dst.Filters = append(l.dst.Filters, Filter{Field: f.name, Column: f.column, Op: op, Value: value})
+		}
+	}
+
+	return nil
+}
+
+// Zerlegt name[op], ohne Operator wird eq verwendet
+func parseFilterKey(key string) (string, FilterOp, bool) {
+	i := strings.Index(key, "[")
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return key, OpEq, false
+	}
+
+	return key[:i], FilterOp(key[i+1 : len(key)-1]), true
+}
+
+func containsOp(ops []FilterOp, op FilterOp) bool {
+	for _, v := range ops {
+		if v == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f listField) parseValue(op FilterOp, raw string) (interface{}, error) {
+	switch op {
+	case OpIn:
+		var values []interface{}
+		for _, s := range strings.Split(raw, ",") {
+			v, err := f.parseScalar(s)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+
+		return values, nil
+	case OpLike:
+		return raw, nil
+	}
+
+	return f.parseScalar(raw)
+}
+
+func (f listField) parseScalar(s string) (interface{}, error) {
+	v := reflect.New(f.typ).Elem()
+	if err := setValue(v, s); err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
+}
+
+// Erlaubte Felder eines Models, werden pro Typ zwischengespeichert
+func modelListFields(model interface{}) (map[string]listField, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expected struct was %T", model)
+	}
+
+	if fields, ok := listFields.Load(t); ok {
+		return fields.(map[string]listField), nil
+	}
+
+	fields := map[string]listField{}
+	if err := collectListFields(t, fields); err != nil {
+		return nil, err
+	}
+
+	listFields.Store(t, fields)
+
+	return fields, nil
+}
+
+func collectListFields(t reflect.Type, fields map[string]listField) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("list")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				if err := collectListFields(sf.Type, fields); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		f := listField{
+			name:   strings.SplitN(sf.Tag.Get("json"), ",", 2)[0],
+			column: strings.SplitN(sf.Tag.Get("db"), ",", 2)[0],
+			typ:    sf.Type,
+		}
+		if f.name == "" || f.name == "-" {
+			f.name = sf.Name
+		}
+		if f.column == "" || f.column == "-" {
+			f.column = strings.ToLower(sf.Name)
+		}
+
+		for f.typ.Kind() == reflect.Ptr {
+			f.typ = f.typ.Elem()
+		}
+
+		for _, o := range strings.Split(tag, ",") {
+			switch {
+			case o == "sort":
+				f.sortable = true
+			case o == "filter":
+				f.ops = defaultFilterOps(f.typ)
+			case strings.HasPrefix(o, "filter="):
+				for _, op := range strings.Split(strings.TrimPrefix(o, "filter="), "|") {
+					op := FilterOp(op)
+					if op != OpLike && op != OpIn && sqlOperators[op] == "" {
+						return fmt.Errorf("Unknown filter operator %v on %v", op, sf.Name)
+					}
+					if op == OpLike && f.typ.Kind() != reflect.String {
+						return fmt.Errorf("Operator like needs string field %v", sf.Name)
+					}
+					f.ops = append(f.ops, op)
+				}
+			}
+		}
+
+		if !isSafeColumn(f.column) {
+			return fmt.Errorf("Invalid column name %v", f.column)
+		}
+
+		fields[f.name] = f
+	}
+
+	return nil
+}
+
+func defaultFilterOps(t reflect.Type) []FilterOp {
+	switch t.Kind() {
+	case reflect.String:
+		return []FilterOp{OpEq, OpNe, OpIn, OpLike}
+	case reflect.Bool:
+		return []FilterOp{OpEq, OpNe}
+	}
+
+	if t.Kind() == reflect.Struct && t != reflect.TypeOf(time.Time{}) {
+		return []FilterOp{OpEq, OpNe}
+	}
+
+	return []FilterOp{OpEq, OpNe, OpIn, OpGt, OpGte, OpLt, OpLte}
+}
+
+// Spalten stammen aus Struct Tags, trotzdem nur einfache Bezeichner erlauben
+func isSafeColumn(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if !(c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WHERE Bedingung mit ? Platzhaltern, leer ohne Filter. Mit sqlx.DB.Rebind
+// können die Platzhalter an die Datenbank angepasst werden. Bei like steht *
+// für beliebige Zeichen, % und _ aus der Eingabe werden mit ! maskiert, da \
+// nicht in allen Datenbanken als Escape Zeichen funktioniert.
+func (q ListQuery) Where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	for _, f := range q.Filters {
+		switch f.Op {
+		case OpIn:
+			values := f.Value.([]interface{})
+			conds = append(conds, fmt.Sprintf("%v IN (%v)", f.Column, strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")))
+			args = append(args, values...)
+		case OpLike:
+			conds = append(conds, f.Column+" LIKE ? ESCAPE '!'")
+			args = append(args, likePattern(f.Value.(string)))
+		default:
+			conds = append(conds, fmt.Sprintf("%v %v ?", f.Column, sqlOperators[f.Op]))
+			args = append(args, f.Value)
+		}
+	}
+
+	if len(conds) == 0 {
+		return "", nil
+	}
+
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// ORDER BY Klausel, leer ohne Sortierung
+func (q ListQuery) OrderBy() string {
+	if len(q.Sort) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, s := range q.Sort {
+		dir := "ASC"
+		if s.Desc {
+			dir = "DESC"
+		}
+		parts = append(parts, s.Column+" "+dir)
+	}
+
+	return "ORDER BY " + strings.Join(parts, ", ")
+}
+
+// Hängt WHERE und ORDER BY an base an, z.B. "SELECT * FROM monsters"
+func (q ListQuery) SQL(base string) (string, []interface{}) {
+	where, args := q.Where()
+
+	s := base
+	if where != "" {
+		s += " " + where
+	}
+	if order := q.OrderBy(); order != "" {
+		s += " " + order
+	}
+
+	return s, args
+}
+
+func likePattern(s string) string {
+	r := strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`, `*`, `%`)
+	return r.Replace(s)
+}
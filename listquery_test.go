@@ -0,0 +1,180 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type listMonster struct {
+	ID        int64     `json:"id" db:"id" list:"sort"`
+	Name      string    `json:"name" db:"name" list:"sort,filter=eq|like|in"`
+	Cuteness  int       `json:"cuteness" db:"cuteness" list:"sort,filter"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" list:"filter=gte|lte"`
+	Secret    string    `json:"secret" db:"secret"`
+}
+
+func Test_ListQuery(t *testing.T) {
+	created := time.Date(2017, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		Query          string
+		Expected       ListQuery
+		ExpectedStatus int
+	}{
+		{Query: "limit=10"},
+		{
+			Query: "sort=%2Bname,-cuteness",
+			Expected: ListQuery{Sort: []SortField{
+				{Field: "name", Column: "name"},
+				{Field: "cuteness", Column: "cuteness", Desc: true},
+			}},
+		},
+		{
+			Query: "sort=+name",
+			Expected: ListQuery{Sort: []SortField{
+				{Field: "name", Column: "name"},
+			}},
+		},
+		{
+			Query: "sort=-cuteness,name",
+			Expected: ListQuery{Sort: []SortField{
+				{Field: "cuteness", Column: "cuteness", Desc: true},
+				{Field: "name", Column: "name"},
+			}},
+		},
+		{
+			Query: "cuteness[gte]=10&name[like]=Fl*&created_at[lte]=2017-05-01T00:00:00Z",
+			Expected: ListQuery{Filters: []Filter{
+				{Field: "created_at", Column: "created_at", Op: OpLte, Value: created},
+				{Field: "cuteness", Column: "cuteness", Op: OpGte, Value: 10},
+				{Field: "name", Column: "name", Op: OpLike, Value: "Fl*"},
+			}},
+		},
+		{
+			Query: "name=Fluffy&cuteness[in]=1,2",
+			Expected: ListQuery{Filters: []Filter{
+				{Field: "cuteness", Column: "cuteness", Op: OpIn, Value: []interface{}{1, 2}},
+				{Field: "name", Column: "name", Op: OpEq, Value: "Fluffy"},
+			}},
+		},
+		{Query: "sort=secret", ExpectedStatus: http.StatusBadRequest},
+		{Query: "sort=created_at", ExpectedStatus: http.StatusBadRequest},
+		{Query: "secret[eq]=x", ExpectedStatus: http.StatusBadRequest},
+		{Query: "name[gt]=x", ExpectedStatus: http.StatusBadRequest},
+		{Query: "cuteness[like]=1", ExpectedStatus: http.StatusBadRequest},
+		{Query: "cuteness[gte]=many", ExpectedStatus: http.StatusBadRequest},
+		{Query: "created_at=2017-05-01T00:00:00Z", ExpectedStatus: http.StatusBadRequest},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monsters?"+v.Query, &bytes.Buffer{})
+
+		var list ListQuery
+		err := Request(req).ListQuery(listMonster{}, &list).Process()
+		if v.ExpectedStatus != 0 {
+			if err == nil {
+				t.Fatalf("%v: Expected error", i)
+			}
+
+			if status, _, _ := errorStatus(err); status != v.ExpectedStatus {
+				t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(list, v.Expected) {
+			t.Fatalf("%v: Expected %+v was %+v", i, v.Expected, list)
+		}
+	}
+}
+
+func Test_ListQuerySQL(t *testing.T) {
+	list := ListQuery{
+		Sort: []SortField{
+			{Field: "cuteness", Column: "cuteness", Desc: true},
+			{Field: "name", Column: "name"},
+		},
+		Filters: []Filter{
+			{Field: "cuteness", Column: "cuteness", Op: OpGte, Value: 10},
+			{Field: "name", Column: "name", Op: OpLike, Value: `Fl*_100%!`},
+			{Field: "id", Column: "id", Op: OpIn, Value: []interface{}{1, 2}},
+		},
+	}
+
+	// Run test
+	{
+		query, args := list.SQL("SELECT * FROM monsters")
+		expected := `SELECT * FROM monsters WHERE cuteness >= ? AND name LIKE ? ESCAPE '!' AND id IN (?, ?) ORDER BY cuteness DESC, name ASC`
+		if query != expected {
+			t.Fatalf("Expected %v was %v", expected, query)
+		}
+
+		expectedArgs := []interface{}{10, `Fl%!_100!%!!`, 1, 2}
+		if !reflect.DeepEqual(args, expectedArgs) {
+			t.Fatalf("Expected %v was %v", expectedArgs, args)
+		}
+
+		if query, args := (ListQuery{}).SQL("SELECT * FROM monsters"); query != "SELECT * FROM monsters" || args != nil {
+			t.Fatalf("Expected plain query was %v %v", query, args)
+		}
+	}
+}
+
+func Test_ListQuerySQLite(t *testing.T) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.MustExec(`CREATE TABLE monsters (id INTEGER, name TEXT, cuteness INTEGER, created_at TIMESTAMP, secret TEXT)`)
+	db.MustExec(`INSERT INTO monsters VALUES
+		(1, 'Fluffy', 10, '2017-01-01', ''),
+		(2, 'Fl_ppy', 20, '2017-01-01', ''),
+		(3, 'Gruffalo', 30, '2017-01-01', ''),
+		(4, 'Wow!', 5, '2017-01-01', '')`)
+
+	tc := []struct {
+		Query       string
+		ExpectedIDs []int64
+	}{
+		{Query: "sort=-cuteness", ExpectedIDs: []int64{3, 2, 1, 4}},
+		{Query: "name[like]=Fl*&sort=id", ExpectedIDs: []int64{1, 2}},
+		// _ ist kein Platzhalter
+		{Query: "name[like]=Fl_*", ExpectedIDs: []int64{2}},
+		{Query: "name[like]=*!", ExpectedIDs: []int64{4}},
+		{Query: "name[in]=Fluffy,Gruffalo&cuteness[gt]=10", ExpectedIDs: []int64{3}},
+		// Eingaben landen nie im SQL
+		{Query: "name=x'%20OR%20'1'='1", ExpectedIDs: nil},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monsters?"+v.Query, &bytes.Buffer{})
+
+		var list ListQuery
+		if err := Request(req).ListQuery(&listMonster{}, &list).Process(); err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		query, args := list.SQL("SELECT id FROM monsters")
+		var ids []int64
+		if err := db.Select(&ids, db.Rebind(query), args...); err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(ids, v.ExpectedIDs) {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedIDs, ids)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package hrr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Konfiguration für Paginate
+	PageConfig struct {
+		// Standard ist 20
+		DefaultLimit int
+		// Größere Limits werden mit 400 Bad Request abgelehnt, Standard ist 100
+		MaxLimit int
+		// Secret mit dem Cursor signiert werden, ohne Secret sind nur limit und offset erlaubt
+		CursorSecret []byte
+	}
+
+	// Gelesene Paginierung eines Requests, entweder Offset oder Cursor
+	Pagination struct {
+		Limit  int
+		Offset int
+
+		cursor []byte
+		config PageConfig
+	}
+
+	// Eine Seite für Response.Page
+	Page struct {
+		Items interface{}
+		// Gesamtzahl aller Einträge, nil wenn unbekannt
+		Total *int64
+		// Cursor der nächsten und vorherigen Seite, nil wenn es keine gibt.
+		// Werden als JSON kodiert und signiert.
+		NextCursor interface{}
+		PrevCursor interface{}
+	}
+
+	pageResponse struct {
+		Items  interface{} `json:"items"`
+		Total  *int64      `json:"total,omitempty"`
+		Limit  int         `json:"limit"`
+		Offset *int        `json:"offset,omitempty"`
+		Next   string      `json:"next,omitempty"`
+		Prev   string      `json:"prev,omitempty"`
+	}
+
+	paginationRequest struct {
+		page   *Pagination
+		config PageConfig
+	}
+)
+
+var (
+	errInvalidCursor  = errors.New("Invalid cursor")
+	errCursorDisabled = errors.New("Cursor pagination not configured")
+)
+
+// Lese limit und offset oder cursor aus der Query in page
+func (r *request) Paginate(page *Pagination, config PageConfig) *request {
+	r.pagination = &paginationRequest{page: page, config: config}
+	return r
+}
+
+// Liefert true wenn der Request einen Cursor enthält und dekodiert ihn in v
+func (p *Pagination) Cursor(v interface{}) (bool, error) {
+	if p.cursor == nil {
+		return false, nil
+	}
+
+	return true, json.Unmarshal(p.cursor, v)
+}
+
+func (p paginationRequest) parse(query url.Values) Error {
+	config := p.config.withDefaults()
+	*p.page = Pagination{Limit: config.DefaultLimit, config: config}
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return NewError("Error cannot parse int parameter limit", err)
+		}
+
+		if limit > config.MaxLimit {
+			return NewError(fmt.Sprintf("Error limit larger than %v", config.MaxLimit), nil)
+		}
+
+		p.page.Limit = limit
+	}
+
+	cursor := query.Get("cursor")
+	offset := query.Get("offset")
+	if cursor != "" && offset != "" {
+		return NewError("Error use either cursor or offset", nil)
+	}
+
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return NewError("Error cannot parse int parameter offset", err)
+		}
+
+		p.page.Offset = n
+	}
+
+	if cursor != "" {
+		if len(config.CursorSecret) == 0 {
+			return NewError("Error invalid cursor", errCursorDisabled)
+		}
+
+		payload, err := verifyCursor(config.CursorSecret, cursor)
+		if err != nil {
+			return NewError("Error invalid cursor", err)
+		}
+
+		p.page.cursor = payload
+	}
+
+	return nil
+}
+
+func (c PageConfig) withDefaults() PageConfig {
+	if c.DefaultLimit == 0 {
+		c.DefaultLimit = 20
+	}
+
+	if c.MaxLimit == 0 {
+		c.MaxLimit = 100
+	}
+
+	if c.DefaultLimit > c.MaxLimit {
+		c.DefaultLimit = c.MaxLimit
+	}
+
+	return c
+}
+
+// Sende eine Seite mit items, total, limit und Links auf die nächste und vorherige
+// Seite. Die Links werden zusätzlich als Link Header (RFC 8288) gesendet.
+// Ist p nil oder nicht mit Paginate gelesen werden die Standardwerte verwendet.
+func (r *response) Page(p *Pagination, fn func() (Page, Error)) {
+	if p == nil {
+		p = &Pagination{}
+	}
+
+	if p.Limit == 0 {
+		config := p.config.withDefaults()
+		p = &Pagination{Limit: config.DefaultLimit, Offset: p.Offset, cursor: p.cursor, config: config}
+	}
+
+	r.Data(func() (interface{}, Error) {
+		page, err := fn()
+		if err != nil {
+			return nil, err
+		}
+
+		resp := pageResponse{
+			Items: page.Items,
+			Total: page.Total,
+			Limit: p.Limit,
+		}
+
+		if len(p.config.CursorSecret) > 0 && (p.cursor != nil || page.NextCursor != nil || page.PrevCursor != nil) {
+			if resp.Next, err = r.cursorLink(p, page.NextCursor); err != nil {
+				return nil, err
+			}
+
+			if resp.Prev, err = r.cursorLink(p, page.PrevCursor); err != nil {
+				return nil, err
+			}
+		} else {
+			offset := p.Offset
+			resp.Offset = &offset
+			resp.Next, resp.Prev = r.offsetLinks(p, page)
+		}
+
+		var links []string
+		if resp.Next != "" {
+			links = append(links, fmt.Sprintf(`<%v>; rel="next"`, resp.Next))
+		}
+		if resp.Prev != "" {
+			links = append(links, fmt.Sprintf(`<%v>; rel="prev"`, resp.Prev))
+		}
+		if len(links) > 0 {
+			r.response.Header().Set("Link", strings.Join(links, ", "))
+		}
+
+		return resp, nil
+	})
+}
+
+func (r *response) offsetLinks(p *Pagination, page Page) (string, string) {
+	var next, prev string
+
+	hasNext := false
+	if page.Total != nil {
+		hasNext = int64(p.Offset+p.Limit) < *page.Total
+	} else {
+		// Ohne Gesamtzahl gibt es eine nächste Seite wenn diese voll ist
+		v := reflect.ValueOf(page.Items)
+		hasNext = (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Len() >= p.Limit
+	}
+
+	if hasNext {
+		next = r.pageURL(p.Limit, "offset", strconv.Itoa(p.Offset+p.Limit))
+	}
+
+	if p.Offset > 0 {
+		offset := p.Offset - p.Limit
+		if offset < 0 {
+			offset = 0
+		}
+
+		prev = r.pageURL(p.Limit, "offset", strconv.Itoa(offset))
+	}
+
+	return next, prev
+}
+
+func (r *response) cursorLink(p *Pagination, cursor interface{}) (string, Error) {
+	if cursor == nil {
+		return "", nil
+	}
+
+	signed, err := signCursor(p.config.CursorSecret, cursor)
+	if err != nil {
+		return "", Internal("Error cannot encode cursor", err)
+	}
+
+	return r.pageURL(p.Limit, "cursor", signed), nil
+}
+
+// URL des Requests mit neuer Paginierung, andere Query Parameter bleiben erhalten
+func (r *response) pageURL(limit int, key, value string) string {
+	query := r.request.URL.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set(key, value)
+
+	u := url.URL{Path: r.request.URL.Path, RawQuery: query.Encode()}
+
+	return u.String()
+}
+
+// Cursor der Form base64(json).base64(hmac)
+func signCursor(secret []byte, v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func verifyCursor(secret []byte, cursor string) ([]byte, error) {
+	i := strings.Index(cursor, ".")
+	if i < 0 {
+		return nil, errInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(cursor[:i])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(cursor[i+1:])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidCursor
+	}
+
+	return payload, nil
+}
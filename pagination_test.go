@@ -0,0 +1,174 @@
+package hrr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func Test_Paginate(t *testing.T) {
+	secret := []byte("secret")
+	cursor, _ := signCursor(secret, map[string]int{"after": 42})
+	forged, _ := signCursor([]byte("other"), map[string]int{"after": 42})
+
+	tc := []struct {
+		Query          string
+		Config         PageConfig
+		ExpectedLimit  int
+		ExpectedOffset int
+		ExpectedAfter  int
+		ExpectedError  bool
+	}{
+		{ExpectedLimit: 20},
+		{Config: PageConfig{DefaultLimit: 200, MaxLimit: 50}, ExpectedLimit: 50},
+		{Query: "limit=10&offset=30", ExpectedLimit: 10, ExpectedOffset: 30},
+		{Query: "limit=101", ExpectedError: true},
+		{Query: "limit=0", ExpectedError: true},
+		{Query: "offset=-1", ExpectedError: true},
+		{Query: "limit=x", ExpectedError: true},
+		{Query: "cursor=" + cursor, Config: PageConfig{CursorSecret: secret}, ExpectedLimit: 20, ExpectedAfter: 42},
+		{Query: "cursor=" + forged, Config: PageConfig{CursorSecret: secret}, ExpectedError: true},
+		{Query: "cursor=abc", Config: PageConfig{CursorSecret: secret}, ExpectedError: true},
+		{Query: "cursor=" + cursor, ExpectedError: true},
+		{Query: "cursor=" + cursor + "&offset=1", Config: PageConfig{CursorSecret: secret}, ExpectedError: true},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monsters?"+v.Query, &bytes.Buffer{})
+
+		var page Pagination
+		err := Request(req).Paginate(&page, v.Config).Process()
+		if v.ExpectedError {
+			if err == nil {
+				t.Fatalf("%v: Expected error", i)
+			}
+
+			if status, _, _ := errorStatus(err); status != http.StatusBadRequest {
+				t.Fatalf("%v: Expected bad request was %v", i, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if page.Limit != v.ExpectedLimit || page.Offset != v.ExpectedOffset {
+			t.Fatalf("%v: Expected %v/%v was %v/%v", i, v.ExpectedLimit, v.ExpectedOffset, page.Limit, page.Offset)
+		}
+
+		var c struct{ After int }
+		ok, e := page.Cursor(&c)
+		if e != nil || ok != (v.ExpectedAfter != 0) || c.After != v.ExpectedAfter {
+			t.Fatalf("%v: Expected cursor %v was (%v, %v, %v)", i, v.ExpectedAfter, ok, c.After, e)
+		}
+	}
+}
+
+func Test_ResponsePage(t *testing.T) {
+	secret := []byte("secret")
+	next, _ := signCursor(secret, map[string]int{"after": 3})
+	total := int64(5)
+
+	tc := []struct {
+		Query        string
+		Config       PageConfig
+		Page         Page
+		ExpectedBody string
+		ExpectedLink string
+	}{
+		{
+			Query:        "sorted_by=name&limit=2",
+			Page:         Page{Items: []int{1, 2}, Total: &total},
+			ExpectedBody: `{"items":[1,2],"total":5,"limit":2,"offset":0,"next":"/v0/monsters?limit=2&offset=2&sorted_by=name"}`,
+			ExpectedLink: `</v0/monsters?limit=2&offset=2&sorted_by=name>; rel="next"`,
+		},
+		{
+			Query:        "limit=2&offset=3",
+			Page:         Page{Items: []int{4, 5}, Total: &total},
+			ExpectedBody: `{"items":[4,5],"total":5,"limit":2,"offset":3,"prev":"/v0/monsters?limit=2&offset=1"}`,
+			ExpectedLink: `</v0/monsters?limit=2&offset=1>; rel="prev"`,
+		},
+		// Ohne Gesamtzahl gibt es eine nächste Seite wenn die Seite voll ist
+		{
+			Query:        "limit=2&offset=1",
+			Page:         Page{Items: []int{2, 3}},
+			ExpectedBody: `{"items":[2,3],"limit":2,"offset":1,"next":"/v0/monsters?limit=2&offset=3","prev":"/v0/monsters?limit=2&offset=0"}`,
+			ExpectedLink: `</v0/monsters?limit=2&offset=3>; rel="next", </v0/monsters?limit=2&offset=0>; rel="prev"`,
+		},
+		{
+			Query:        "limit=3",
+			Config:       PageConfig{CursorSecret: secret},
+			Page:         Page{Items: []int{1, 2, 3}, NextCursor: map[string]int{"after": 3}},
+			ExpectedBody: `{"items":[1,2,3],"limit":3,"next":"/v0/monsters?cursor=` + next + `&limit=3"}`,
+			ExpectedLink: `</v0/monsters?cursor=` + next + `&limit=3>; rel="next"`,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monsters?"+v.Query, &bytes.Buffer{})
+
+		var page Pagination
+		if err := Request(req).Paginate(&page, v.Config).Process(); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := httptest.NewRecorder()
+		Response(resp, req).Page(&page, func() (Page, Error) {
+			return v.Page, nil
+		})
+
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%v: Expected 200 was %v", i, resp.Code)
+		}
+
+		var expected, body interface{}
+		json.Unmarshal([]byte(v.ExpectedBody), &expected)
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || !reflect.DeepEqual(body, expected) {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedBody, resp.Body)
+		}
+
+		if resp.Header().Get("Link") != v.ExpectedLink {
+			t.Fatalf("%v: Expected Link %v was %v", i, v.ExpectedLink, resp.Header().Get("Link"))
+		}
+	}
+}
+
+func Test_ResponsePageDefaults(t *testing.T) {
+	tc := []struct {
+		Page         *Pagination
+		ExpectedBody string
+	}{
+		{Page: nil, ExpectedBody: `{"items":[1,2],"limit":20,"offset":0}`},
+		{Page: &Pagination{}, ExpectedBody: `{"items":[1,2],"limit":20,"offset":0}`},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/v0/monsters", &bytes.Buffer{})
+		resp := httptest.NewRecorder()
+
+		Response(resp, req).Page(v.Page, func() (Page, Error) {
+			return Page{Items: []int{1, 2}}, nil
+		})
+
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%v: Expected 200 was %v", i, resp.Code)
+		}
+
+		var expected, body interface{}
+		json.Unmarshal([]byte(v.ExpectedBody), &expected)
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || !reflect.DeepEqual(body, expected) {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedBody, resp.Body)
+		}
+
+		if link := resp.Header().Get("Link"); link != "" {
+			t.Fatalf("%v: Expected no Link was %v", i, link)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package hrr
+
+import (
+	"encoding"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type (
+	// Liefert den Wert eines URL Parameters anhand seines Namens.
+	// httprouter.Params und gin.Params erfüllen das Interface bereits.
+	ParamSource interface {
+		ByName(name string) string
+	}
+
+	// Funktion als ParamSource verwenden
+	ParamSourceFunc func(name string) string
+
+	// Map als ParamSource verwenden, z.B. für gorilla/mux Vars
+	ParamMap map[string]string
+
+	// Wandelt einen beliebigen Parameter Speicher in eine ParamSource um.
+	// Ist der Speicher nicht bekannt wird false zurückgegeben.
+	ParamSourceConverter func(p interface{}) (ParamSource, bool)
+)
+
+var (
+	errEmptyParam = errors.New("Empty parameter")
+
+	paramSourcesMu sync.RWMutex
+	paramSources   = []ParamSourceConverter{builtinParamSource}
+)
+
+// Registriere eine Funktion die eigene Parameter Speicher in eine ParamSource umwandelt.
+// Zuletzt registrierte Funktionen werden zuerst gefragt.
+func RegisterParamSource(fn ParamSourceConverter) {
+	paramSourcesMu.Lock()
+	defer paramSourcesMu.Unlock()
+
+	paramSources = append([]ParamSourceConverter{fn}, paramSources...)
+}
+
+// Parameter aus gorilla/mux
+func MuxVars(r *http.Request) ParamSource {
+	return ParamMap(mux.Vars(r))
+}
+
+// Parameter aus chi
+func ChiURLParams(r *http.Request) ParamSource {
+	return ParamSourceFunc(func(name string) string {
+		return chi.URLParam(r, name)
+	})
+}
+
+// Parameter aus http.ServeMux Patterns (ab Go 1.22)
+func PathValues(r *http.Request) ParamSource {
+	return ParamSourceFunc(r.PathValue)
+}
+
+func (fn ParamSourceFunc) ByName(name string) string {
+	return fn(name)
+}
+
+func (m ParamMap) ByName(name string) string {
+	return m[name]
+}
+
+func paramSource(p interface{}) (ParamSource, bool) {
+	paramSourcesMu.RLock()
+	defer paramSourcesMu.RUnlock()
+
+	for _, fn := range paramSources {
+		if s, ok := fn(p); ok {
+			return s, true
+		}
+	}
+
+	return nil, false
+}
+
+func builtinParamSource(p interface{}) (ParamSource, bool) {
+	switch v := p.(type) {
+	case ParamSource:
+		return v, true
+	case map[string]string:
+		return ParamMap(v), true
+	case *http.Request:
+		return PathValues(v), true
+	}
+
+	return nil, false
+}
+
+// Setze Daten um später einen string Parameter aus der URL zu lesen.
+// Ein leerer Parameter gilt als nicht gefunden.
+func (r *request) ParamString(p interface{}, name string, s *string) *request {
+	return r.param(p, name, "string", func(v string) error {
+		if v == "" {
+			return errEmptyParam
+		}
+
+		*s = v
+
+		return nil
+	})
+}
+
+// Setze Daten um später einen uint64 Parameter aus der URL zu lesen
+func (r *request) ParamUint64(p interface{}, name string, u *uint64) *request {
+	return r.param(p, name, "uint64", func(v string) error {
+		tmp, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		*u = tmp
+
+		return nil
+	})
+}
+
+// Setze Daten um später einen float64 Parameter aus der URL zu lesen
+func (r *request) ParamFloat64(p interface{}, name string, f *float64) *request {
+	return r.param(p, name, "float64", func(v string) error {
+		tmp, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+
+		*f = tmp
+
+		return nil
+	})
+}
+
+// Setze Daten um später einen bool Parameter aus der URL zu lesen
+func (r *request) ParamBool(p interface{}, name string, b *bool) *request {
+	return r.param(p, name, "bool", func(v string) error {
+		tmp, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+
+		*b = tmp
+
+		return nil
+	})
+}
+
+// Setze Daten um später eine UUID aus der URL zu lesen
+func (r *request) ParamUUID(p interface{}, name string, u *uuid.UUID) *request {
+	return r.param(p, name, "uuid", func(v string) error {
+		tmp, err := uuid.Parse(v)
+		if err != nil {
+			return err
+		}
+
+		*u = tmp
+
+		return nil
+	})
+}
+
+// Setze Daten um später einen Zeitpunkt im übergebenen Layout aus der URL zu lesen
+func (r *request) ParamTime(p interface{}, name, layout string, t *time.Time) *request {
+	return r.param(p, name, "time", func(v string) error {
+		tmp, err := time.Parse(layout, v)
+		if err != nil {
+			return err
+		}
+
+		*t = tmp
+
+		return nil
+	})
+}
+
+// Setze Daten um später einen beliebigen Parameter aus der URL zu lesen.
+// Der Parameter wird mit UnmarshalText des übergebenen Objekts dekodiert.
+func (r *request) ParamText(p interface{}, name string, t encoding.TextUnmarshaler) *request {
+	return r.param(p, name, "text", func(v string) error {
+		if v == "" {
+			return errEmptyParam
+		}
+
+		return t.UnmarshalText([]byte(v))
+	})
+}
+
+func (r *request) param(p interface{}, name, kind string, fn func(string) error) *request {
+	r.params = append(r.params, &param{
+		data:  p,
+		name:  name,
+		kind:  kind,
+		parse: fn,
+	})
+
+	return r
+}
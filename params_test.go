@@ -0,0 +1,259 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/julienschmidt/httprouter"
+)
+
+func Test_ParamTypes(t *testing.T) {
+	tc := struct {
+		URL          string
+		ExpectedSlug string
+		ExpectedUint uint64
+		ExpectedF    float64
+		ExpectedBool bool
+		ExpectedUUID uuid.UUID
+		ExpectedTime time.Time
+	}{
+		URL:          "/fluffy/7/1.5/true/5b52d1a6-2ac3-4c2a-9d8f-4a5f4d8e0f11/2016-01-02",
+		ExpectedSlug: "fluffy",
+		ExpectedUint: 7,
+		ExpectedF:    1.5,
+		ExpectedBool: true,
+		ExpectedUUID: uuid.MustParse("5b52d1a6-2ac3-4c2a-9d8f-4a5f4d8e0f11"),
+		ExpectedTime: time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Run test
+	{
+		var called bool
+		router := httprouter.New()
+		router.GET("/:slug/:u/:f/:b/:uuid/:date", func(w http.ResponseWriter, r *http.Request, p httprouter.Params) {
+			var slug string
+			var u uint64
+			var f float64
+			var b bool
+			var id uuid.UUID
+			var date time.Time
+			err := Request(r).
+				ParamString(p, "slug", &slug).
+				ParamUint64(p, "u", &u).
+				ParamFloat64(p, "f", &f).
+				ParamBool(p, "b", &b).
+				ParamUUID(p, "uuid", &id).
+				ParamTime(p, "date", "2006-01-02", &date).
+				Process()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if slug != tc.ExpectedSlug ||
+				u != tc.ExpectedUint ||
+				f != tc.ExpectedF ||
+				b != tc.ExpectedBool ||
+				id != tc.ExpectedUUID ||
+				!date.Equal(tc.ExpectedTime) {
+				t.Fatalf("Expected %v was (%v, %v, %v, %v, %v, %v)", tc, slug, u, f, b, id, date)
+			}
+
+			called = true
+		})
+
+		req := NewRequest(t, "GET", tc.URL, &bytes.Buffer{})
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !called {
+			t.Fatal("Expected handler to be called")
+		}
+	}
+}
+
+func Test_ParamText(t *testing.T) {
+	tc := struct {
+		Expected uuid.UUID
+	}{
+		Expected: uuid.MustParse("5b52d1a6-2ac3-4c2a-9d8f-4a5f4d8e0f11"),
+	}
+
+	// Run test
+	{
+		var result uuid.UUID
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		p := httprouter.Params{{Key: "id", Value: tc.Expected.String()}}
+		err := Request(req).ParamText(p, "id", &result).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result != tc.Expected {
+			t.Fatalf("Expected %v was %v", tc.Expected, result)
+		}
+	}
+}
+
+func Test_ParamError(t *testing.T) {
+	tc := []struct {
+		Value    string
+		Expected string
+		Bind     func(*request, httprouter.Params) *request
+	}{
+		{
+			Value:    "",
+			Expected: "Error cannot find string parameter x",
+			Bind: func(r *request, p httprouter.Params) *request {
+				var s string
+				return r.ParamString(p, "x", &s)
+			},
+		},
+		{
+			Value:    "-1",
+			Expected: "Error cannot find uint64 parameter x",
+			Bind: func(r *request, p httprouter.Params) *request {
+				var u uint64
+				return r.ParamUint64(p, "x", &u)
+			},
+		},
+		{
+			Value:    "yes please",
+			Expected: "Error cannot find bool parameter x",
+			Bind: func(r *request, p httprouter.Params) *request {
+				var b bool
+				return r.ParamBool(p, "x", &b)
+			},
+		},
+		{
+			Value:    "42",
+			Expected: "Error cannot find uuid parameter x",
+			Bind: func(r *request, p httprouter.Params) *request {
+				var u uuid.UUID
+				return r.ParamUUID(p, "x", &u)
+			},
+		},
+		{
+			Value:    "02.01.2016",
+			Expected: "Error cannot find time parameter x",
+			Bind: func(r *request, p httprouter.Params) *request {
+				var d time.Time
+				return r.ParamTime(p, "x", time.RFC3339, &d)
+			},
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		p := httprouter.Params{{Key: "x", Value: v.Value}}
+		err := v.Bind(Request(req), p).Process()
+		if err == nil || err.Message() != v.Expected {
+			t.Fatalf("Expected %v was %v", v.Expected, err)
+		}
+	}
+}
+
+func Test_ParamSources(t *testing.T) {
+	tc := struct {
+		URL      string
+		Expected int64
+	}{
+		URL:      "/monster/42",
+		Expected: 42,
+	}
+
+	check := func(name string, h http.Handler) {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, NewRequest(t, "GET", tc.URL, &bytes.Buffer{}))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("%v: Expected %v was %v", name, http.StatusOK, resp.Code)
+		}
+	}
+
+	handler := func(source func(*http.Request) interface{}) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			var id int64
+			err := Request(r).ParamInt64(source(r), "id", &id).Process()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if id != tc.Expected {
+				t.Fatalf("Expected %v was %v", tc.Expected, id)
+			}
+
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+
+	// Run test
+	{
+		serveMux := http.NewServeMux()
+		serveMux.Handle("GET /monster/{id}", handler(func(r *http.Request) interface{} {
+			return r
+		}))
+		check("ServeMux", serveMux)
+
+		muxRouter := mux.NewRouter()
+		muxRouter.Handle("/monster/{id}", handler(func(r *http.Request) interface{} {
+			return MuxVars(r)
+		}))
+		check("gorilla/mux", muxRouter)
+
+		chiRouter := chi.NewRouter()
+		chiRouter.Get("/monster/{id}", handler(func(r *http.Request) interface{} {
+			return ChiURLParams(r)
+		}))
+		check("chi", chiRouter)
+	}
+}
+
+type customParams struct {
+	values []string
+}
+
+func Test_RegisterParamSource(t *testing.T) {
+	tc := struct {
+		Store    customParams
+		Expected string
+	}{
+		Store:    customParams{values: []string{"id", "fluffy"}},
+		Expected: "fluffy",
+	}
+
+	// Run test
+	{
+		RegisterParamSource(func(p interface{}) (ParamSource, bool) {
+			c, ok := p.(customParams)
+			if !ok {
+				return nil, false
+			}
+
+			return ParamSourceFunc(func(name string) string {
+				for i := 0; i+1 < len(c.values); i += 2 {
+					if c.values[i] == name {
+						return c.values[i+1]
+					}
+				}
+
+				return ""
+			}), true
+		})
+
+		var result string
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		err := Request(req).ParamString(tc.Store, "id", &result).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if result != tc.Expected {
+			t.Fatalf("Expected %v was %v", tc.Expected, result)
+		}
+	}
+}
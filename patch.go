@@ -0,0 +1,598 @@
+package hrr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Ein PATCH Request der auf die aktuelle Ressource angewendet wird
+	patchRequest struct {
+		target  interface{}
+		load    func() Error
+		changed *[]string
+	}
+
+	// Eine Operation eines JSON Patch (RFC 6902)
+	patchOperation struct {
+		Op   string `json:"op"`
+		Path string `json:"path"`
+		From string `json:"from"`
+		// null bleibt als "null" erhalten, fehlt value ist es leer
+		Value json.RawMessage `json:"value"`
+	}
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var (
+	errPatchPath = errors.New("Invalid patch path")
+	errPatchTest = errors.New("Patch test failed")
+)
+
+// Wende einen PATCH Body auf target an. load muss target mit der aktuellen Ressource
+// füllen, danach wird application/merge-patch+json (RFC 7396) oder
+// application/json-patch+json (RFC 6902) angewendet und das Ergebnis mit den
+// Regeln von ValidateBody geprüft. changed erhält die geänderten Felder als
+// JSON Pointer, z.B. /name, und darf nil sein.
+func (r *request) Patch(target interface{}, load func() Error, changed *[]string) *request {
+	r.patch = &patchRequest{target: target, load: load, changed: changed}
+	return r
+}
+
+func (r *request) applyPatch() Error {
+	contentType := r.request.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != MergePatchType && mediaType != JSONPatchType {
+		return unsupportedMediaType(contentType)
+	}
+
+	if err := r.patch.load(); err != nil {
+		return err
+	}
+
+	current, e := json.Marshal(r.patch.target)
+	if e != nil {
+		return Internal("Error cannot encode resource", e)
+	}
+
+	original, e := decodeJSONDocument(current)
+	if e != nil {
+		return Internal("Error cannot encode resource", e)
+	}
+
+	doc, _ := decodeJSONDocument(current)
+
+	var err Error
+	if mediaType == MergePatchType {
+		patch, e := decodeJSONDocument(r.body)
+		if e != nil {
+			return NewError("Error cannot parse merge patch", e)
+		}
+
+		doc = mergePatch(doc, patch)
+	} else {
+		doc, err = r.jsonPatch(doc)
+		if err != nil {
+			return err
+		}
+	}
+
+	patched, e := json.Marshal(doc)
+	if e != nil {
+		return Internal("Error cannot encode patched resource", e)
+	}
+
+	// In eine Kopie der geladenen Ressource dekodieren, so bleiben Felder ohne
+	// JSON Darstellung wie json:"-" erhalten. Entfernte Felder erhalten ihren Nullwert.
+	result := reflect.New(reflect.TypeOf(r.patch.target).Elem())
+	result.Elem().Set(reflect.ValueOf(r.patch.target).Elem())
+	clearRemovedJSON(result.Elem(), original, doc)
+
+	if r.strict {
+		err = strictUnmarshal(patched, result.Interface())
+	} else if e := json.Unmarshal(patched, result.Interface()); e != nil {
+		err = jsonDecodeError(e)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Erst validieren, bei einem Fehler bleibt die geladene Ressource unverändert
+	if err := r.engine.validateStruct(result.Interface()); err != nil {
+		return err
+	}
+
+	if r.patch.changed != nil {
+		// Vergleich mit dem dekodierten Ergebnis, unbekannte Felder zählen nicht
+		updated, e := json.Marshal(result.Interface())
+		if e != nil {
+			return Internal("Error cannot encode patched resource", e)
+		}
+
+		doc, _ = decodeJSONDocument(updated)
+		*r.patch.changed = diffJSON(original, doc, "")
+	}
+
+	reflect.ValueOf(r.patch.target).Elem().Set(result.Elem())
+
+	return nil
+}
+
+// Setzt alle Felder von v auf ihren Nullwert die in patched fehlen oder null
+// sind. Geänderte Arrays werden geleert, da json.Unmarshal vorhandene Elemente
+// sonst nur überschreibt.
+func clearRemovedJSON(v reflect.Value, original, patched interface{}) {
+	if patched == nil {
+		if v.CanSet() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	if _, ok := patched.([]interface{}); ok {
+		if v.Kind() == reflect.Slice && v.CanSet() && !reflect.DeepEqual(original, patched) {
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return
+	}
+
+	mo, okO := original.(map[string]interface{})
+	mp, okP := patched.(map[string]interface{})
+	if !okO || !okP {
+		return
+	}
+
+	for k, o := range mo {
+		p, ok := mp[k]
+		if v.Kind() == reflect.Map {
+			if !ok || p == nil {
+				if v.Type().Key().Kind() == reflect.String {
+					v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), reflect.Value{})
+				}
+			}
+			// Werte einer Map werden von json.Unmarshal vollständig ersetzt
+			continue
+		}
+
+		field, found := jsonFieldValue(v, k)
+		if !found {
+			continue
+		}
+
+		if !ok {
+			p = nil
+		}
+
+		clearRemovedJSON(field, o, p)
+	}
+}
+
+// Wert des Feldes mit dem JSON Namen key, false wenn es fehlt oder in einem
+// nil Pointer eingebettet ist
+func jsonFieldValue(v reflect.Value, key string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	sf, ok := jsonField(v.Type(), key)
+	if !ok {
+		return reflect.Value{}, false
+	}
+
+	for i, index := range sf.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(index)
+	}
+
+	return v, true
+}
+
+func (r *request) jsonPatch(doc interface{}) (interface{}, Error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(r.body, &ops); err != nil {
+		return nil, NewError("Error cannot parse JSON patch", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err == errPatchTest {
+			msg := fmt.Sprintf("Error patch operation %v test failed at %v", i, op.Path)
+			return nil, NewStatusError(http.StatusConflict, "patch_test_failed", msg, err).
+				WithExtension("operation", i)
+		}
+		if err != nil {
+			msg := fmt.Sprintf("Error cannot apply patch operation %v %v %v", i, op.Op, op.Path)
+			return nil, NewStatusError(http.StatusUnprocessableEntity, "patch_failed", msg, err).
+				WithExtension("operation", i)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	value := func() (interface{}, error) {
+		if len(op.Value) == 0 {
+			return nil, errors.New("Missing value")
+		}
+
+		return decodeJSONDocument(op.Value)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+
+		return addPointer(doc, op.Path, v)
+	case "remove":
+		doc, _, err := removePointer(doc, op.Path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Path == "" {
+			return v, nil
+		}
+
+		if _, err := getPointer(doc, op.Path); err != nil {
+			return nil, err
+		}
+
+		doc, _, err = removePointer(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		return addPointer(doc, op.Path, v)
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errPatchPath
+		}
+
+		doc, v, err := removePointer(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		return addPointer(doc, op.Path, v)
+	case "copy":
+		v, err := getPointer(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		return addPointer(doc, op.Path, deepCopyJSON(v))
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+
+		current, err := getPointer(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalJSON(current, v) {
+			return nil, errPatchTest
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("Unknown patch operation %v", op.Op)
+}
+
+// Merge Patch nach RFC 7396, null entfernt ein Feld
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = mergePatch(t[k], v)
+	}
+
+	return t
+}
+
+// Zerlegt einen JSON Pointer (RFC 6901)
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errPatchPath
+	}
+
+	parts := strings.Split(pointer[1:], "/")
+	for i, p := range parts {
+		parts[i] = strings.Replace(strings.Replace(p, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return parts, nil
+}
+
+func getPointer(doc interface{}, pointer string) (interface{}, error) {
+	parts, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range parts {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			child, ok := v[p]
+			if !ok {
+				return nil, errPatchPath
+			}
+			doc = child
+		case []interface{}:
+			i, err := arrayIndex(p, len(v)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, errPatchPath
+		}
+	}
+
+	return doc, nil
+}
+
+// Fügt value an pointer ein und liefert das geänderte Dokument
+func addPointer(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	parts, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 0 {
+		return value, nil
+	}
+
+	parent, err := getPointer(doc, joinPointer(parts[:len(parts)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	key := parts[len(parts)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[key] = value
+	case []interface{}:
+		i := len(v)
+		if key != "-" {
+			if i, err = arrayIndex(key, len(v)); err != nil {
+				return nil, err
+			}
+		}
+
+		v = append(v, nil)
+		copy(v[i+1:], v[i:])
+		v[i] = value
+
+		return setPointer(doc, parts[:len(parts)-1], v)
+	default:
+		return nil, errPatchPath
+	}
+
+	return doc, nil
+}
+
+// Entfernt den Wert an pointer, liefert das geänderte Dokument und den entfernten Wert
+func removePointer(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	parts, err := splitPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(parts) == 0 {
+		return nil, nil, errPatchPath
+	}
+
+	parent, err := getPointer(doc, joinPointer(parts[:len(parts)-1]))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := parts[len(parts)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		removed, ok := v[key]
+		if !ok {
+			return nil, nil, errPatchPath
+		}
+		delete(v, key)
+
+		return doc, removed, nil
+	case []interface{}:
+		i, err := arrayIndex(key, len(v)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		removed := v[i]
+		v = append(v[:i:i], v[i+1:]...)
+		doc, err := setPointer(doc, parts[:len(parts)-1], v)
+
+		return doc, removed, err
+	}
+
+	return nil, nil, errPatchPath
+}
+
+// Ersetzt den Wert an parts, nötig weil Arrays beim Ändern neu angelegt werden
+func setPointer(doc interface{}, parts []string, value interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+
+	parent, err := getPointer(doc, joinPointer(parts[:len(parts)-1]))
+	if err != nil {
+		return nil, err
+	}
+
+	key := parts[len(parts)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[key] = value
+	case []interface{}:
+		i, err := arrayIndex(key, len(v)-1)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = value
+	default:
+		return nil, errPatchPath
+	}
+
+	return doc, nil
+}
+
+func joinPointer(parts []string) string {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString("/")
+		b.WriteString(strings.Replace(strings.Replace(p, "~", "~0", -1), "/", "~1", -1))
+	}
+
+	return b.String()
+}
+
+func arrayIndex(s string, max int) (int, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, errPatchPath
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 || i > max {
+		return 0, errPatchPath
+	}
+
+	return i, nil
+}
+
+// Dekodiert JSON mit json.Number, damit Zahlen unverändert bleiben
+func decodeJSONDocument(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopyJSON(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = deepCopyJSON(e)
+		}
+		return a
+	}
+
+	return v
+}
+
+func equalJSON(a, b interface{}) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+
+		return errX == nil && errY == nil && fx == fy
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// JSON Pointer aller geänderten Werte, Objekte werden rekursiv verglichen
+func diffJSON(a, b interface{}, path string) []string {
+	ma, okA := a.(map[string]interface{})
+	mb, okB := b.(map[string]interface{})
+	if !okA || !okB {
+		if equalJSON(a, b) {
+			return nil
+		}
+
+		if path == "" {
+			return []string{"/"}
+		}
+
+		return []string{path}
+	}
+
+	var changed []string
+	for k, v := range ma {
+		changed = append(changed, diffJSON(v, mb[k], path+joinPointer([]string{k}))...)
+	}
+
+	for k, v := range mb {
+		if _, ok := ma[k]; !ok {
+			changed = append(changed, diffJSON(nil, v, path+joinPointer([]string{k}))...)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
@@ -0,0 +1,302 @@
+package hrr
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type patchMonster struct {
+	Name     string   `json:"name" validate:"required"`
+	Cuteness int      `json:"cuteness"`
+	Tags     []string `json:"tags"`
+}
+
+func Test_Patch(t *testing.T) {
+	tc := []struct {
+		ContentType     string
+		Body            string
+		Missing         bool
+		Expected        patchMonster
+		ExpectedChanged []string
+		ExpectedStatus  int
+	}{
+		{
+			ContentType:     MergePatchType,
+			Body:            `{"cuteness":5}`,
+			Expected:        patchMonster{Name: "Gruffalo", Cuteness: 5, Tags: []string{"forest"}},
+			ExpectedChanged: []string{"/cuteness"},
+		},
+		{
+			ContentType:     MergePatchType,
+			Body:            `{"tags":null,"cuteness":1}`,
+			Expected:        patchMonster{Name: "Gruffalo", Cuteness: 1},
+			ExpectedChanged: []string{"/tags"},
+		},
+		{
+			ContentType:    MergePatchType,
+			Body:           `{"name":null}`,
+			Expected:       patchMonster{Name: "Gruffalo", Cuteness: 1, Tags: []string{"forest"}},
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			ContentType: JSONPatchType,
+			Body: `[
+				{"op":"test","path":"/name","value":"Gruffalo"},
+				{"op":"replace","path":"/cuteness","value":7},
+				{"op":"add","path":"/tags/-","value":"cave"}
+			]`,
+			Expected:        patchMonster{Name: "Gruffalo", Cuteness: 7, Tags: []string{"forest", "cave"}},
+			ExpectedChanged: []string{"/cuteness", "/tags"},
+		},
+		{
+			ContentType:    JSONPatchType,
+			Body:           `[{"op":"test","path":"/cuteness","value":2}]`,
+			ExpectedStatus: http.StatusConflict,
+		},
+		{
+			ContentType:    JSONPatchType,
+			Body:           `[{"op":"remove","path":"/tags/3"}]`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			ContentType:    JSONPatchType,
+			Body:           `[{"op":"jump","path":"/name"}]`,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			ContentType:    JSONPatchType,
+			Body:           `{"op":"remove"}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			ContentType:    "application/json",
+			Body:           `{"cuteness":5}`,
+			ExpectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			ContentType:    MergePatchType,
+			Body:           `{"cuteness":5}`,
+			Missing:        true,
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "PATCH", "/v0/monster/1", bytes.NewBufferString(v.Body))
+		req.Header.Set("Content-Type", v.ContentType)
+
+		var monster patchMonster
+		var changed []string
+		load := func() Error {
+			if v.Missing {
+				return NotFound("Monster not found", nil)
+			}
+
+			monster = patchMonster{Name: "Gruffalo", Cuteness: 1, Tags: []string{"forest"}}
+			return nil
+		}
+
+		err := Request(req).Patch(&monster, load, &changed).Process()
+		if v.ExpectedStatus != 0 {
+			if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+				t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+			}
+
+			// Eine ungültige Änderung lässt die Ressource unverändert
+			if v.Expected.Name != "" && (!reflect.DeepEqual(monster, v.Expected) || changed != nil) {
+				t.Fatalf("%v: Expected %+v was %+v, changed %v", i, v.Expected, monster, changed)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(monster, v.Expected) {
+			t.Fatalf("%v: Expected %+v was %+v", i, v.Expected, monster)
+		}
+
+		if !reflect.DeepEqual(changed, v.ExpectedChanged) {
+			t.Fatalf("%v: Expected changed %v was %v", i, v.ExpectedChanged, changed)
+		}
+	}
+}
+
+type patchOwner struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type patchResource struct {
+	Name     string            `json:"name"`
+	Owner    patchOwner        `json:"owner"`
+	Parent   *patchOwner       `json:"parent"`
+	Labels   map[string]string `json:"labels"`
+	Password string            `json:"-"`
+	version  int
+}
+
+func Test_PatchKeepsHiddenFields(t *testing.T) {
+	tc := []struct {
+		ContentType string
+		Body        string
+		Expected    patchResource
+	}{
+		{
+			ContentType: MergePatchType,
+			Body:        `{"name":"Yeti","owner":{"email":null},"parent":null,"labels":{"a":null}}`,
+			Expected: patchResource{
+				Name:     "Yeti",
+				Owner:    patchOwner{Name: "Krake"},
+				Labels:   map[string]string{"b": "2"},
+				Password: "secret",
+				version:  3,
+			},
+		},
+		{
+			ContentType: JSONPatchType,
+			Body: `[
+				{"op":"remove","path":"/owner/name"},
+				{"op":"remove","path":"/parent"},
+				{"op":"replace","path":"/name","value":null}
+			]`,
+			Expected: patchResource{
+				Owner:    patchOwner{Email: "krake@example.com"},
+				Labels:   map[string]string{"a": "1", "b": "2"},
+				Password: "secret",
+				version:  3,
+			},
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "PATCH", "/v0/monster/1", bytes.NewBufferString(v.Body))
+		req.Header.Set("Content-Type", v.ContentType)
+
+		var resource patchResource
+		load := func() Error {
+			resource = patchResource{
+				Name:     "Gruffalo",
+				Owner:    patchOwner{Name: "Krake", Email: "krake@example.com"},
+				Parent:   &patchOwner{Name: "Yeti"},
+				Labels:   map[string]string{"a": "1", "b": "2"},
+				Password: "secret",
+				version:  3,
+			}
+			return nil
+		}
+
+		if err := Request(req).Patch(&resource, load, nil).Process(); err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+
+		if !reflect.DeepEqual(resource, v.Expected) {
+			t.Fatalf("%v: Expected %+v was %+v", i, v.Expected, resource)
+		}
+	}
+}
+
+func Test_JSONPatchOperations(t *testing.T) {
+	tc := []struct {
+		Doc      string
+		Patch    string
+		Expected string
+	}{
+		// Beispiele aus RFC 6902 Anhang A
+		{
+			Doc:      `{"foo":["bar","baz"]}`,
+			Patch:    `[{"op":"add","path":"/foo/1","value":"qux"}]`,
+			Expected: `{"foo":["bar","qux","baz"]}`,
+		},
+		{
+			Doc:      `{"foo":["bar","qux","baz"]}`,
+			Patch:    `[{"op":"remove","path":"/foo/1"}]`,
+			Expected: `{"foo":["bar","baz"]}`,
+		},
+		{
+			Doc:      `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			Patch:    `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			Expected: `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`,
+		},
+		{
+			Doc:      `{"foo":["all","grass","cows","eat"]}`,
+			Patch:    `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			Expected: `{"foo":["all","cows","eat","grass"]}`,
+		},
+		{
+			Doc:      `{"/":1,"~":2}`,
+			Patch:    `[{"op":"copy","from":"/~1","path":"/~0"}]`,
+			Expected: `{"/":1,"~":1}`,
+		},
+		{
+			Doc:      `{"foo":"bar"}`,
+			Patch:    `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`,
+			Expected: `{"foo":"bar","child":{"grandchild":{}}}`,
+		},
+		{
+			Doc:      `{"foo":1}`,
+			Patch:    `[{"op":"replace","path":"","value":[1,2]}]`,
+			Expected: `[1,2]`,
+		},
+	}
+
+	// Run test
+	for i, v := range tc {
+		doc, err := decodeJSONDocument([]byte(v.Doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ops []patchOperation
+		if err := json.Unmarshal([]byte(v.Patch), &ops); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, op := range ops {
+			doc, err = applyOperation(doc, op)
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+		}
+
+		expected, _ := decodeJSONDocument([]byte(v.Expected))
+		if !reflect.DeepEqual(doc, expected) {
+			t.Fatalf("%v: Expected %v was %v", i, expected, doc)
+		}
+	}
+}
+
+func Test_MergePatch(t *testing.T) {
+	// Beispiele aus RFC 7396 Anhang A
+	tc := []struct {
+		Doc      string
+		Patch    string
+		Expected string
+	}{
+		{Doc: `{"a":"b"}`, Patch: `{"a":"c"}`, Expected: `{"a":"c"}`},
+		{Doc: `{"a":"b"}`, Patch: `{"b":"c"}`, Expected: `{"a":"b","b":"c"}`},
+		{Doc: `{"a":"b","b":"c"}`, Patch: `{"a":null}`, Expected: `{"b":"c"}`},
+		{Doc: `{"a":[{"b":"c"}]}`, Patch: `{"a":[1]}`, Expected: `{"a":[1]}`},
+		{Doc: `{"a":{"b":"c"}}`, Patch: `{"a":{"b":"d","c":null}}`, Expected: `{"a":{"b":"d"}}`},
+		{Doc: `["a","b"]`, Patch: `["c","d"]`, Expected: `["c","d"]`},
+		{Doc: `{"e":null}`, Patch: `{"a":1}`, Expected: `{"e":null,"a":1}`},
+		{Doc: `{}`, Patch: `{"a":{"bb":{"ccc":null}}}`, Expected: `{"a":{"bb":{}}}`},
+	}
+
+	// Run test
+	for i, v := range tc {
+		doc, _ := decodeJSONDocument([]byte(v.Doc))
+		patch, _ := decodeJSONDocument([]byte(v.Patch))
+		expected, _ := decodeJSONDocument([]byte(v.Expected))
+
+		if result := mergePatch(doc, patch); !reflect.DeepEqual(result, expected) {
+			t.Fatalf("%v: Expected %v was %v", i, expected, result)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package hrr
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Regel die entscheidet ob der angemeldete Aufrufer einen Request ausführen darf.
+	// id ist nil wenn der Request anonym ist.
+	Policy struct {
+		Name  string
+		Allow func(r *http.Request, id *Identity) (bool, error)
+	}
+)
+
+// Prüfe die übergebenen Regeln nach der Anmeldung und dem Binden der Parameter.
+// Wird eine Regel verletzt liefert Process 403 Forbidden.
+func (r *request) Require(policies ...Policy) *request {
+	r.policies = append(r.policies, policies...)
+
+	return r
+}
+
+// Eigene Regel
+func PolicyFunc(name string, fn func(r *http.Request, id *Identity) (bool, error)) Policy {
+	return Policy{Name: name, Allow: fn}
+}
+
+// Aufrufer muss angemeldet sein
+func Authenticated() Policy {
+	return PolicyFunc("authenticated", func(_ *http.Request, id *Identity) (bool, error) {
+		return id != nil, nil
+	})
+}
+
+// Aufrufer muss alle Rollen haben
+func Roles(roles ...string) Policy {
+	return PolicyFunc("roles:"+strings.Join(roles, ","), func(_ *http.Request, id *Identity) (bool, error) {
+		for _, role := range roles {
+			if !id.HasRole(role) {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+}
+
+// Aufrufer muss mindestens eine der Rollen haben
+func AnyRole(roles ...string) Policy {
+	return PolicyFunc("any_role:"+strings.Join(roles, ","), func(_ *http.Request, id *Identity) (bool, error) {
+		for _, role := range roles {
+			if id.HasRole(role) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	})
+}
+
+// Aufrufer muss alle Scopes haben
+func Scopes(scopes ...string) Policy {
+	return PolicyFunc("scopes:"+strings.Join(scopes, ","), func(_ *http.Request, id *Identity) (bool, error) {
+		for _, scope := range scopes {
+			if !id.HasScope(scope) {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	})
+}
+
+// Aufrufer muss Besitzer der Ressource sein. owner liefert die ID des Besitzers,
+// z.B. anhand einer mit ParamInt64 gebundenen ID.
+func Owner(owner func() (string, error)) Policy {
+	return PolicyFunc("owner", func(_ *http.Request, id *Identity) (bool, error) {
+		if id == nil {
+			return false, nil
+		}
+
+		o, err := owner()
+		if err != nil {
+			return false, err
+		}
+
+		return o == id.ID, nil
+	})
+}
+
+// Prüfe alle Regeln
+func (r *request) authorize() Error {
+	id := Principal(r.request)
+	for _, p := range r.policies {
+		ok, err := p.Allow(r.request, id)
+		if err != nil {
+			return Internal("Error cannot check permissions", err)
+		}
+
+		if !ok {
+			msg := fmt.Sprintf("Forbidden by policy %v", p.Name)
+			return Forbidden(msg, nil).WithExtension("policy", p.Name)
+		}
+	}
+
+	return nil
+}
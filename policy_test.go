@@ -0,0 +1,129 @@
+package hrr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func Test_Require(t *testing.T) {
+	keeper := &Identity{ID: "1", Roles: []string{"keeper"}, Scopes: []string{"monsters:read"}}
+
+	tc := []struct {
+		Identity       *Identity
+		Policies       []Policy
+		ExpectedStatus int
+		ExpectedMsg    string
+	}{
+		{
+			Identity: keeper,
+			Policies: []Policy{Authenticated(), Roles("keeper"), Scopes("monsters:read")},
+		},
+		{
+			Identity: keeper,
+			Policies: []Policy{AnyRole("admin", "keeper")},
+		},
+		{
+			Identity:       nil,
+			Policies:       []Policy{Authenticated()},
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedMsg:    "Forbidden by policy authenticated",
+		},
+		{
+			Identity:       keeper,
+			Policies:       []Policy{Roles("keeper", "admin")},
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedMsg:    "Forbidden by policy roles:keeper,admin",
+		},
+		{
+			Identity:       keeper,
+			Policies:       []Policy{Scopes("monsters:write")},
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedMsg:    "Forbidden by policy scopes:monsters:write",
+		},
+		{
+			Identity: keeper,
+			Policies: []Policy{PolicyFunc("broken", func(*http.Request, *Identity) (bool, error) {
+				return false, errors.New("db down")
+			})},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedMsg:    "Error cannot check permissions",
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		id := v.Identity
+
+		r := Request(req)
+		if id != nil {
+			r.BaseAuthIdentity(func(string, string) (*Identity, error) {
+				return id, nil
+			})
+		}
+
+		err := r.Require(v.Policies...).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		s, ok := err.(StatusError)
+		if !ok || s.Status() != v.ExpectedStatus || err.Message() != v.ExpectedMsg {
+			t.Fatalf("Expected (%v, %v) was %v", v.ExpectedStatus, v.ExpectedMsg, err)
+		}
+	}
+}
+
+func Test_RequireOwner(t *testing.T) {
+	owners := map[int64]string{1: "wolverine", 2: "deadpool"}
+
+	tc := []struct {
+		MonsterID int64
+		Expected  bool
+	}{
+		{MonsterID: 1, Expected: true},
+		{MonsterID: 2, Expected: false},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "PUT", "/", bytes.NewBufferString(`{"value": "v"}`))
+		req.SetBasicAuth("wolverine", "claws")
+		p := httprouter.Params{{Key: "monsterID", Value: fmt.Sprint(v.MonsterID)}}
+
+		var id int64
+		var body TestObject
+		err := Request(req).
+			Put(&body, p, "monsterID", &id).
+			BaseAuth(func(string, string) (bool, error) {
+				return true, nil
+			}).
+			Require(Owner(func() (string, error) {
+				return owners[id], nil
+			})).
+			Process()
+
+		if v.Expected && err != nil {
+			t.Fatal(err)
+		}
+
+		if !v.Expected {
+			s, ok := err.(StatusError)
+			if !ok || s.Status() != http.StatusForbidden {
+				t.Fatalf("Expected %v was %v", http.StatusForbidden, err)
+			}
+
+			if body.Value != "" {
+				t.Fatalf("Expected body not to be decoded was %v", body)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package hrr
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type (
+	// Der angemeldete Aufrufer eines Requests
+	Identity struct {
+		ID     string
+		Roles  []string
+		Scopes []string
+		Claims map[string]interface{}
+	}
+
+	principalKey struct{}
+)
+
+// Erfolgreiche Anmeldung ohne bekannten Aufrufer
+var anonymous = &Identity{}
+
+// Liefert den angemeldeten Aufrufer, nil wenn der Request anonym ist
+func Principal(r *http.Request) *Identity {
+	return PrincipalFromContext(r.Context())
+}
+
+// Liefert den angemeldeten Aufrufer aus einem Context
+func PrincipalFromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(principalKey{}).(*Identity)
+	return id
+}
+
+// Legt den Aufrufer im Context ab
+func ContextWithPrincipal(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, principalKey{}, id)
+}
+
+// Aktiviere Base Auth, fn liefert den angemeldeten Aufrufer oder nil
+func (r *request) BaseAuthIdentity(fn func(user, password string) (*Identity, error)) *request {
+	r.authenticate = r.basicAuth(fn)
+
+	return r
+}
+
+// Aktiviere Bearer Token Auth, fn liefert den angemeldeten Aufrufer oder nil
+func (r *request) BearerAuthIdentity(fn func(token string) (*Identity, error)) *request {
+	r.authenticate = r.bearerAuth(fn)
+
+	return r
+}
+
+func (i *Identity) HasRole(role string) bool {
+	return i != nil && contains(i.Roles, role)
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	return i != nil && contains(i.Scopes, scope)
+}
+
+// Legt den Aufrufer im Context des Requests ab
+func (r *request) setPrincipal(id *Identity) {
+	r.setContext(ContextWithPrincipal(r.request.Context(), id))
+}
+
+// Ersetzt den Context des Requests. Der vom Aufrufer übergebene *http.Request
+// wird dabei überschrieben, damit Handler und Response(w, r) mit demselben
+// Pointer auf Aufrufer und Idempotency-Key zugreifen können, siehe Process.
+func (r *request) setContext(ctx context.Context) {
+	*r.request = *r.request.WithContext(ctx)
+}
+
+// Aufrufer aus den Claims eines JWT, Rollen stehen in roles, Scopes in scope oder scp
+func identityFromClaims(c Claims) *Identity {
+	id := &Identity{Claims: c}
+	id.ID, _ = c["sub"].(string)
+	id.Roles = claimStrings(c["roles"])
+
+	if s, ok := c["scope"].(string); ok {
+		id.Scopes = strings.Fields(s)
+	} else {
+		id.Scopes = claimStrings(c["scp"])
+	}
+
+	return id
+}
+
+func claimStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return strings.Fields(t)
+	case []interface{}:
+		var s []string
+		for _, x := range t {
+			if str, ok := x.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
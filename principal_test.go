@@ -0,0 +1,173 @@
+package hrr
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func Test_PrincipalBaseAuth(t *testing.T) {
+	tc := struct {
+		User     string
+		Password string
+	}{
+		User:     "Deadpool",
+		Password: ":)",
+	}
+
+	// Run test
+	{
+		logger, mock := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth(tc.User, tc.Password)
+
+		if Principal(req) != nil {
+			t.Fatal("Expected anonymous request")
+		}
+
+		err := Request(req).BaseAuth(func(string, string) (bool, error) {
+			return true, nil
+		}).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id := Principal(req)
+		if id == nil || id.ID != tc.User {
+			t.Fatalf("Expected %v was %v", tc.User, id)
+		}
+
+		Response(httptest.NewRecorder(), req).Error(NewError("Fail", errors.New("fail")))
+		if p := mock.LastEntry().Data["principal"]; p != tc.User {
+			t.Fatalf("Expected %v was %v", tc.User, p)
+		}
+	}
+}
+
+func Test_PrincipalBaseAuthIdentity(t *testing.T) {
+	tc := struct {
+		Expected Identity
+	}{
+		Expected: Identity{ID: "42", Roles: []string{"admin"}},
+	}
+
+	// Run test
+	{
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("wolverine", "claws")
+
+		err := Request(req).BaseAuthIdentity(func(user, pass string) (*Identity, error) {
+			id := tc.Expected
+			return &id, nil
+		}).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id := Principal(req)
+		if id == nil || id.ID != tc.Expected.ID || !id.HasRole("admin") || id.HasRole("keeper") {
+			t.Fatalf("Expected %v was %v", tc.Expected, id)
+		}
+	}
+}
+
+func Test_PrincipalAnonymous(t *testing.T) {
+	// Run test
+	{
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		if err := Request(req).Process(); err != nil {
+			t.Fatal(err)
+		}
+
+		if id := Principal(req); id != nil {
+			t.Fatalf("Expected anonymous was %v", id)
+		}
+
+		req.Header.Set("Authorization", "Bearer x")
+		err := Request(req).BearerAuth(func(string) (bool, error) {
+			return true, nil
+		}).Process()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if id := Principal(req); id != nil {
+			t.Fatalf("Expected anonymous was %v", id)
+		}
+	}
+}
+
+func Test_PrincipalWithoutAuth(t *testing.T) {
+	tc := []struct {
+		Engine *Engine
+	}{
+		{Engine: defaultEngine()},
+		{Engine: NewEngine()},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("wolverine", "anything")
+
+		err := v.Engine.Request(req).Require(Authenticated()).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusForbidden {
+			t.Fatalf("%v: Expected 403 was %v", i, err)
+		}
+
+		if id := Principal(req); id != nil {
+			t.Fatalf("%v: Expected anonymous was %v", i, id)
+		}
+	}
+}
+
+func Test_PrincipalJWT(t *testing.T) {
+	tc := struct {
+		Claims jwt.MapClaims
+	}{
+		Claims: jwt.MapClaims{
+			"sub":   "fluffy",
+			"roles": []string{"monster"},
+			"scope": "monsters:read monsters:write",
+			"exp":   time.Now().Add(time.Minute).Unix(),
+		},
+	}
+
+	// Run test
+	{
+		key := []byte("secret")
+		v, err := NewJWTVerifier(JWTConfig{HMACKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tc.Claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		if err := Request(req).JWTAuth(v, nil).Process(); err != nil {
+			t.Fatal(err)
+		}
+
+		id := Principal(req)
+		if id == nil ||
+			id.ID != "fluffy" ||
+			!id.HasRole("monster") ||
+			!id.HasScope("monsters:write") ||
+			id.Claims["sub"] != "fluffy" {
+			t.Fatalf("Expected %v was %v", tc.Claims, id)
+		}
+	}
+}
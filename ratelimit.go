@@ -0,0 +1,309 @@
+package hrr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// Algorithmus mit dem ein RateLimitStore Requests zählt
+	RateLimitAlgorithm int
+
+	// Liefert den Schlüssel unter dem Requests gezählt werden
+	RateLimitKey func(r *http.Request) (string, error)
+
+	// Erlaubt Limit Requests pro Window und Schlüssel
+	RateLimitPolicy struct {
+		// Pflicht, Policies mit gleichem Namen teilen sich ihre Zähler
+		Name string
+		// Limit und Window müssen größer 0 sein
+		Limit     int
+		Window    time.Duration
+		Algorithm RateLimitAlgorithm
+		// Ohne Angabe wird vor der Anmeldung nach IP Adresse gezählt,
+		// sonst nach der Anmeldung
+		Key RateLimitKey
+		// Ohne Angabe wird ein gemeinsamer Speicher im Prozess verwendet
+		Store RateLimitStore
+	}
+
+	// Ergebnis einer Zählung
+	RateLimitResult struct {
+		Allowed   bool
+		Limit     int
+		Remaining int
+		// Zeit bis das Limit wieder vollständig verfügbar ist
+		Reset time.Duration
+		// Zeit bis der nächste Request erlaubt ist, nur wenn Allowed false ist
+		RetryAfter time.Duration
+	}
+
+	// Speicher für Zähler, gemeinsame Speicher z.B. in Redis müssen Take atomar umsetzen
+	RateLimitStore interface {
+		Take(key string, policy RateLimitPolicy) (RateLimitResult, error)
+	}
+
+	// Zähler im Speicher des Prozesses
+	MemoryRateLimitStore struct {
+		mu        sync.Mutex
+		buckets   map[string]*tokenBucket
+		windows   map[string]*slidingWindow
+		lastSweep time.Time
+	}
+
+	tokenBucket struct {
+		tokens float64
+		last   time.Time
+		window time.Duration
+	}
+
+	slidingWindow struct {
+		start    time.Time
+		current  int
+		previous int
+		window   time.Duration
+	}
+)
+
+const (
+	// Bucket mit Limit Tokens, wird gleichmäßig über Window aufgefüllt
+	TokenBucket RateLimitAlgorithm = iota
+	// Gewichtete Summe aus aktuellem und vorherigem Fenster
+	SlidingWindow
+)
+
+var (
+	errRateLimited       = errors.New("Rate limit exceeded")
+	errRateLimitName     = errors.New("Rate limit policy without name")
+	errRateLimitInterval = errors.New("Rate limit policy needs limit and window greater 0")
+
+	rateLimitStore = NewMemoryRateLimitStore()
+)
+
+// Begrenze die Anzahl Requests. Wird ein Limit überschritten liefert Process
+// 429 Too Many Requests mit Retry-After und RateLimit-* Headern.
+// Ungültige Policies führen in Process zu 500 Internal Server Error.
+func (r *request) RateLimit(policies ...RateLimitPolicy) *request {
+	for _, p := range policies {
+		if err := p.validate(); err != nil && r.rateLimitErr == nil {
+			r.rateLimitErr = Internal("Error invalid rate limit policy", err)
+		}
+	}
+
+	r.rateLimits = append(r.rateLimits, policies...)
+
+	return r
+}
+
+func (p RateLimitPolicy) validate() error {
+	if p.Name == "" {
+		return errRateLimitName
+	}
+
+	if p.Limit <= 0 || p.Window <= 0 {
+		return fmt.Errorf("%v: %v", p.Name, errRateLimitInterval)
+	}
+
+	return nil
+}
+
+// Zähle nach IP Adresse aus RemoteAddr
+func KeyByIP(r *http.Request) (string, error) {
+	return "ip:" + remoteIP(r.RemoteAddr), nil
+}
+
+// Zähle nach angemeldetem Aufrufer, anonyme Requests nach IP Adresse
+func KeyByPrincipal(r *http.Request) (string, error) {
+	if id := Principal(r); id != nil && id.ID != "" {
+		return "principal:" + id.ID, nil
+	}
+
+	return KeyByIP(r)
+}
+
+// Zähle nach API Key aus APIKeyAuth, Requests ohne API Key nach IP Adresse
+func KeyByAPIKey(r *http.Request) (string, error) {
+	if id := Principal(r); id != nil {
+		if prefix, ok := id.Claims["api_key"].(string); ok {
+			return "api_key:" + prefix, nil
+		}
+	}
+
+	return KeyByIP(r)
+}
+
+// Zähle Policies ohne Key (beforeAuth) oder mit Key
+func (r *request) rateLimit(beforeAuth bool) Error {
+	if r.rateLimitErr != nil {
+		return r.rateLimitErr
+	}
+
+	for _, p := range r.rateLimits {
+		if (p.Key == nil) != beforeAuth {
+			continue
+		}
+
+		keyFunc := p.Key
+		if keyFunc == nil {
+			keyFunc = KeyByIP
+		}
+
+		key, err := keyFunc(r.request)
+		if err != nil {
+			return Internal("Error cannot find rate limit key", err)
+		}
+
+		store := p.Store
+		if store == nil {
+			store = rateLimitStore
+		}
+
+		result, err := store.Take(p.Name+"|"+key, p)
+		if err != nil {
+			return Internal("Error cannot check rate limit", err)
+		}
+
+		if !result.Allowed {
+			return TooManyRequests("Too many requests", errRateLimited).
+				WithHeader("Retry-After", seconds(result.RetryAfter)).
+				WithHeader("RateLimit-Limit", strconv.Itoa(result.Limit)).
+				WithHeader("RateLimit-Remaining", strconv.Itoa(result.Remaining)).
+				WithHeader("RateLimit-Reset", seconds(result.Reset)).
+				WithExtension("policy", p.Name)
+		}
+	}
+
+	return nil
+}
+
+// Aufgerundete Sekunden für Header
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*tokenBucket{},
+		windows: map[string]*slidingWindow{},
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, p RateLimitPolicy) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if p.Algorithm == SlidingWindow {
+		w, ok := s.windows[key]
+		if !ok {
+			w = &slidingWindow{start: now.Truncate(p.Window), window: p.Window}
+			s.windows[key] = w
+		}
+
+		return w.take(p.Limit, now), nil
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(p.Limit), last: now, window: p.Window}
+		s.buckets[key] = b
+	}
+
+	return b.take(p.Limit, now), nil
+}
+
+// Entferne Zähler die länger als ihr Fenster nicht verwendet wurden
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for k, b := range s.buckets {
+		if now.Sub(b.last) > b.window {
+			delete(s.buckets, k)
+		}
+	}
+
+	for k, w := range s.windows {
+		if now.Sub(w.start) > 2*w.window {
+			delete(s.windows, k)
+		}
+	}
+}
+
+func (b *tokenBucket) take(limit int, now time.Time) RateLimitResult {
+	rate := float64(limit) / b.window.Seconds()
+
+	b.tokens = math.Min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	result := RateLimitResult{Limit: limit}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsDuration((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = secondsDuration((float64(limit) - b.tokens) / rate)
+
+	return result
+}
+
+func (w *slidingWindow) take(limit int, now time.Time) RateLimitResult {
+	start := now.Truncate(w.window)
+	if !start.Equal(w.start) {
+		if start.Equal(w.start.Add(w.window)) {
+			w.previous = w.current
+		} else {
+			w.previous = 0
+		}
+
+		w.current = 0
+		w.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - elapsed.Seconds()/w.window.Seconds()
+	count := float64(w.previous)*weight + float64(w.current)
+
+	result := RateLimitResult{
+		Limit: limit,
+		Reset: start.Add(w.window).Sub(now),
+	}
+
+	if count+1 <= float64(limit) {
+		w.current++
+		result.Allowed = true
+		result.Remaining = int(float64(limit) - count - 1)
+
+		return result
+	}
+
+	// Zeit bis der Anteil des vorherigen Fensters weit genug gesunken ist
+	if w.current+1 > limit || w.previous == 0 {
+		result.RetryAfter = result.Reset
+	} else {
+		free := float64(limit-w.current-1) / float64(w.previous)
+		wait := secondsDuration((1-free)*w.window.Seconds()) - elapsed
+		result.RetryAfter = wait
+		if wait <= 0 {
+			result.RetryAfter = time.Second
+		}
+	}
+
+	return result
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
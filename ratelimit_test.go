@@ -0,0 +1,258 @@
+package hrr
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+)
+
+func Test_RateLimit(t *testing.T) {
+	tc := []struct {
+		Algorithm RateLimitAlgorithm
+	}{
+		{Algorithm: TokenBucket},
+		{Algorithm: SlidingWindow},
+	}
+
+	// Run test
+	for _, v := range tc {
+		policy := RateLimitPolicy{
+			Name:      "monsters",
+			Limit:     2,
+			Window:    time.Hour,
+			Algorithm: v.Algorithm,
+			Store:     NewMemoryRateLimitStore(),
+		}
+
+		for i, addr := range []string{"10.0.0.1:1", "10.0.0.1:2", "10.0.0.2:1"} {
+			req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+			req.RemoteAddr = addr
+			if err := Request(req).RateLimit(policy).Process(); err != nil {
+				t.Fatalf("%v %v: %v", v.Algorithm, i, err)
+			}
+		}
+
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.RemoteAddr = "10.0.0.1:3"
+		err := Request(req).RateLimit(policy).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusTooManyRequests {
+			t.Fatalf("%v: Expected 429 was %v", v.Algorithm, err)
+		}
+
+		resp := httptest.NewRecorder()
+		Response(resp, req).Error(err)
+
+		retry, _ := strconv.Atoi(resp.Header().Get("Retry-After"))
+		if resp.Code != http.StatusTooManyRequests || retry <= 0 || retry > 3600 {
+			t.Fatalf("%v: Expected Retry-After was %v", v.Algorithm, resp.Header())
+		}
+
+		if resp.Header().Get("RateLimit-Limit") != "2" || resp.Header().Get("RateLimit-Remaining") != "0" {
+			t.Fatalf("%v: Expected RateLimit headers was %v", v.Algorithm, resp.Header())
+		}
+
+		if reset, _ := strconv.Atoi(resp.Header().Get("RateLimit-Reset")); reset <= 0 || reset > 3600 {
+			t.Fatalf("%v: Expected RateLimit-Reset was %v", v.Algorithm, resp.Header())
+		}
+	}
+}
+
+func Test_RateLimitKey(t *testing.T) {
+	policy := RateLimitPolicy{
+		Name:   "principal",
+		Limit:  1,
+		Window: time.Hour,
+		Key:    KeyByPrincipal,
+		Store:  NewMemoryRateLimitStore(),
+	}
+
+	users := map[string]string{"krake": "tinte", "yeti": "schnee"}
+	auth := func(user, password string) (*Identity, error) {
+		if p, ok := users[user]; !ok || p != password {
+			return nil, nil
+		}
+
+		return &Identity{ID: user}, nil
+	}
+
+	tc := []struct {
+		User           string
+		Password       string
+		ExpectedStatus int
+	}{
+		{User: "krake", Password: "tinte"},
+		{User: "yeti", Password: "schnee"},
+		{User: "krake", Password: "tinte", ExpectedStatus: http.StatusTooManyRequests},
+		// Ein ausgedachter Benutzer bekommt keinen eigenen Bucket
+		{User: "krake2", Password: "tinte", ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth(v.User, v.Password)
+
+		err := Request(req).BaseAuthIdentity(auth).RateLimit(policy).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+
+	// Fehler der Key Funktion
+	{
+		policy.Key = func(*http.Request) (string, error) {
+			return "", errors.New("No key")
+		}
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		err := Request(req).RateLimit(policy).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusInternalServerError {
+			t.Fatalf("Expected 500 was %v", err)
+		}
+	}
+}
+
+func Test_KeyByAPIKey(t *testing.T) {
+	req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+	req.RemoteAddr = "10.0.0.1:1"
+
+	// Run test
+	{
+		if key, _ := KeyByAPIKey(req); key != "ip:10.0.0.1" {
+			t.Fatalf("Expected ip:10.0.0.1 was %v", key)
+		}
+
+		req = req.WithContext(ContextWithPrincipal(req.Context(), &Identity{
+			ID:     "service-a",
+			Claims: map[string]interface{}{"api_key": "abc"},
+		}))
+
+		if key, _ := KeyByAPIKey(req); key != "api_key:abc" {
+			t.Fatalf("Expected api_key:abc was %v", key)
+		}
+	}
+}
+
+func Test_RateLimitFailedAuth(t *testing.T) {
+	deny := func(string, string) (bool, error) {
+		return false, nil
+	}
+
+	tc := []struct {
+		Policy RateLimitPolicy
+	}{
+		{Policy: RateLimitPolicy{Name: "ip", Limit: 1, Window: time.Hour}},
+		{Policy: RateLimitPolicy{Name: "principal", Limit: 1, Window: time.Hour, Key: KeyByPrincipal}},
+	}
+
+	// Run test
+	for i, v := range tc {
+		v.Policy.Store = NewMemoryRateLimitStore()
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("krake", "falsch")
+		err := Request(req).BaseAuth(deny).RateLimit(v.Policy).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusUnauthorized {
+			t.Fatalf("%v: Expected 401 was %v", i, err)
+		}
+
+		// Jeder weitere Versuch mit anderem Benutzer wird begrenzt
+		req = NewRequest(t, "GET", "/", &bytes.Buffer{})
+		req.SetBasicAuth("yeti", "falsch")
+		err = Request(req).BaseAuth(deny).RateLimit(v.Policy).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusTooManyRequests {
+			t.Fatalf("%v: Expected 429 was %v", i, err)
+		}
+	}
+}
+
+func Test_RateLimitInvalidPolicy(t *testing.T) {
+	tc := []struct {
+		Policy RateLimitPolicy
+	}{
+		{Policy: RateLimitPolicy{Limit: 10, Window: time.Second}},
+		{Policy: RateLimitPolicy{Name: "zero-window", Limit: 10}},
+		{Policy: RateLimitPolicy{Name: "zero-limit", Window: time.Second}},
+		{Policy: RateLimitPolicy{Name: "negative", Limit: -1, Window: time.Second, Algorithm: SlidingWindow}},
+	}
+
+	// Run test
+	for i, v := range tc {
+		v.Policy.Store = NewMemoryRateLimitStore()
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		err := Request(req).RateLimit(v.Policy).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusInternalServerError {
+			t.Fatalf("%v: Expected 500 was %v", i, err)
+		}
+	}
+}
+
+func Test_RateLimitPolicies(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	second := RateLimitPolicy{Name: "second", Limit: 2, Window: time.Second, Store: store}
+	hour := RateLimitPolicy{Name: "hour", Limit: 3, Window: time.Hour, Store: store}
+
+	// Run test
+	{
+		// Jede Policy zählt in einem eigenen Bucket
+		for i := 0; i < 2; i++ {
+			req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+			if err := Request(req).RateLimit(second, hour).Process(); err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+		}
+
+		if n := len(store.buckets); n != 2 {
+			t.Fatalf("Expected 2 buckets was %v", n)
+		}
+
+		req := NewRequest(t, "GET", "/", &bytes.Buffer{})
+		err := Request(req).RateLimit(second, hour).Process()
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusTooManyRequests {
+			t.Fatalf("Expected 429 was %v", err)
+		}
+	}
+}
+
+func Test_SlidingWindow(t *testing.T) {
+	start := time.Date(2017, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := &slidingWindow{start: start, window: time.Minute}
+
+	// Run test
+	{
+		for i := 0; i < 4; i++ {
+			if r := w.take(4, start.Add(10*time.Second)); !r.Allowed {
+				t.Fatalf("%v: Expected allowed", i)
+			}
+		}
+
+		// Nach der Hälfte des nächsten Fensters zählen die 4 alten Requests zur Hälfte
+		r := w.take(4, start.Add(90*time.Second))
+		if !r.Allowed || r.Remaining != 1 {
+			t.Fatalf("Expected allowed with 1 remaining was %+v", r)
+		}
+
+		w.take(4, start.Add(90*time.Second))
+		r = w.take(4, start.Add(90*time.Second))
+		if r.Allowed || r.RetryAfter != 15*time.Second {
+			t.Fatalf("Expected retry after 15s was %+v", r)
+		}
+	}
+}
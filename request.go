@@ -1,72 +1,118 @@
-// Funktionen um HTTP Requests zu vearbeiten und HTTP Responses zu erstellen. Das Ziel ist es schneller REST APIs zuentwickeln. Es werden momentan nur JSON Bodys verarbeitet. Ebenso werden auch nur JSON Bodys zurückgesendet.
+// Funktionen um HTTP Requests zu vearbeiten und HTTP Responses zu erstellen. Das Ziel ist es schneller REST APIs zuentwickeln. Bodys werden passend zu Content-Type und Accept Header mit einem registrierten Codec verarbeitet, Standard ist JSON.
 package hrr
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strconv"
 
-	"gopkg.in/go-playground/validator.v8"
-
 	"github.com/Sirupsen/logrus"
-	"github.com/gin-gonic/gin"
-	"github.com/julienschmidt/httprouter"
 )
 
 type (
 	authFunc func(string, string) (bool, error)
 
 	request struct {
+		engine             *Engine
 		request            *http.Request
 		logger             *logrus.Logger
 		bodyObject         interface{}
 		body               []byte
-		paramsInt64        []*paramInt64
+		params             []*param
+		bindings           []*binding
 		enableValidateBody bool
-		authFunc           authFunc
+		authenticate       func() (*Identity, Error)
+		maxBodySize        int64
+		streamBody         bool
+		strict             bool
+		policies           []Policy
+		signature          *SignatureConfig
+		rateLimits         []RateLimitPolicy
+		rateLimitErr       Error
+		idempotency        *IdempotencyConfig
+		precondition       VersionFunc
+		patch              *patchRequest
+		pagination         *paginationRequest
+		listQuery          *listRequest
 	}
 
 	requestError struct {
-		message string
-		err     error
+		message    string
+		err        error
+		status     int
+		code       string
+		header     http.Header
+		extensions map[string]interface{}
+		fields     []FieldError
 	}
 
-	paramInt64 struct {
-		data interface{}
-		name string
-		i64  *int64
+	// Ein URL Parameter der erst beim Aufruf von Process gelesen wird
+	param struct {
+		data  interface{}
+		name  string
+		kind  string
+		parse func(string) error
 	}
 
 	Error interface {
 		Message() string
 		error
 	}
+
+	// Optionale Erweiterung von Error um HTTP Status und maschinenlesbaren Code
+	StatusError interface {
+		Error
+		Status() int
+		Code() string
+	}
+
+	// Optionale Erweiterung von Error um Header die mit dem Fehler gesendet werden
+	HeaderError interface {
+		Error
+		Header() http.Header
+	}
 )
 
-// Globale Konfiguration
+// Globale Konfiguration, wird von Request und Response verwendet.
+// Für mehrere unabhängige Konfigurationen siehe Engine.
 var (
 	Logger           = logrus.New()
 	LogAllRequests   = false
 	ValidatorTagName = "validate"
+	AuthRealm        = "Restricted"
+	// Maximale Größe eines Bodys in Bytes, 0 oder negativ bedeutet keine Begrenzung
+	MaxBodySize int64 = 10 << 20
+	// Anzahl Bytes eines gestreamten Bodys die geloggt werden
+	LogBodyPrefix = 1024
+	// Dekodiere alle JSON Bodys streng, siehe Strict
+	StrictDecoding = false
 )
 
 // Konfiguriere neues request Objekt
 func Request(r *http.Request) *request {
-	requestLogger := newLogger(Logger)
+	return defaultEngine().Request(r)
+}
+
+func newRequest(e *Engine, r *http.Request) *request {
+	requestLogger := newLogger(e.logger())
 	requestLogger.Out = ioutil.Discard
 
 	request := &request{
-		request:    r,
-		logger:     requestLogger,
-		bodyObject: nil,
-		authFunc:   allIn,
+		engine:      e,
+		request:     r,
+		logger:      requestLogger,
+		bodyObject:  nil,
+		maxBodySize: e.maxBodySize(),
+		strict:      e.StrictDecoding,
+	}
+	request.authenticate = anonymousAuth
+	if e.DefaultAuth != nil {
+		request.authenticate = request.basicAuth(identityAuth(e.DefaultAuth))
 	}
 
-	if LogAllRequests {
+	if e.LogAllRequests {
 		request.Log()
 	}
 
@@ -76,23 +122,109 @@ func Request(r *http.Request) *request {
 
 // Request soll geloggt werden
 func (r *request) Log() *request {
-	r.logger.Out = Logger.Out
+	r.logger.Out = r.engine.logger().Out
 	return r
 }
 
-// Führe alle definierten Funktionen für den übergeben Request aus
+// Führe alle definierten Funktionen für den übergeben Request aus.
+//
+// Achtung: Process verändert den übergebenen *http.Request. Der angemeldete
+// Aufrufer und ein belegter Idempotency-Key werden in dessen Context abgelegt,
+// damit Principal(r) und Response(w, r) sie mit demselben Pointer finden.
+// Der Request wird dafür mit einer Kopie samt neuem Context überschrieben, er
+// darf während Process nicht in anderen Goroutinen verwendet werden.
 func (r *request) Process() Error {
+	// Policies ohne Key zählen nach IP Adresse vor der Anmeldung,
+	// so werden auch Requests mit falschen Zugangsdaten begrenzt
+	if err := r.rateLimit(true); err != nil {
+		return err
+	}
+
 	if err := r.auth(); err != nil {
+		// Fehlgeschlagene Anmeldungen zählen anonym, z.B. bei KeyByPrincipal nach IP Adresse
+		if limitErr := r.rateLimit(false); limitErr != nil {
+			return limitErr
+		}
+
+		return err
+	}
+
+	// Nach der Anmeldung, damit nach Aufrufer gezählt werden kann
+	if err := r.rateLimit(false); err != nil {
 		return err
 	}
 
-	r.setBody()
-	r.logRequest()
+	stream := r.streamBody && r.bodyObject != nil && r.signature == nil && r.idempotency == nil
+	if !stream {
+		if err := r.setBody(); err != nil {
+			r.logRequest()
+			return err
+		}
 
-	if r.bodyObject != nil {
-		if err := r.decodeBody(); err != nil {
+		r.logRequest()
+	}
+
+	if r.signature != nil {
+		if err := r.verifySignature(); err != nil {
 			return err
 		}
+	}
+
+	for _, v := range r.params {
+		err := r.queryParam(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, v := range r.bindings {
+		err := v.bind(r.engine)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.pagination != nil {
+		if err := r.pagination.parse(r.request.URL.Query()); err != nil {
+			return err
+		}
+	}
+
+	if r.listQuery != nil {
+		if err := r.listQuery.parse(r.request.URL.Query()); err != nil {
+			return err
+		}
+	}
+
+	// Rechte erst prüfen wenn die Parameter gebunden sind, z.B. für Besitzer Prüfungen
+	if err := r.authorize(); err != nil {
+		return err
+	}
+
+	if r.precondition != nil {
+		if err := r.checkPrecondition(); err != nil {
+			return err
+		}
+	}
+
+	if r.patch != nil {
+		if err := r.applyPatch(); err != nil {
+			return err
+		}
+	}
+
+	if r.bodyObject != nil {
+		if stream {
+			err := r.streamDecodeBody()
+			r.logRequest()
+			if err != nil {
+				return err
+			}
+		} else {
+			if err := r.decodeBody(); err != nil {
+				return err
+			}
+		}
 
 		if r.enableValidateBody {
 			err := r.validateBody()
@@ -102,9 +234,9 @@ func (r *request) Process() Error {
 		}
 	}
 
-	for _, v := range r.paramsInt64 {
-		err := r.queryParamInt64(v)
-		if err != nil {
+	// Zuletzt, damit nur gültige Requests einen Schlüssel belegen
+	if r.idempotency != nil {
+		if err := r.idempotent(); err != nil {
 			return err
 		}
 	}
@@ -112,7 +244,7 @@ func (r *request) Process() Error {
 	return nil
 }
 
-// Setze pointer zu Objekt um beim späteren decoden des JSON Bodys Daten darin abzulegen.
+// Setze pointer zu Objekt um beim späteren decoden des Bodys Daten darin abzulegen.
 func (r *request) DecodeBody(body interface{}) *request {
 	r.bodyObject = body
 	return r
@@ -120,13 +252,16 @@ func (r *request) DecodeBody(body interface{}) *request {
 
 // Setze Daten um später params aus URL zu lesen
 func (r *request) ParamInt64(p interface{}, name string, i *int64) *request {
-	r.paramsInt64 = append(r.paramsInt64, &paramInt64{
-		data: p,
-		name: name,
-		i64:  i,
-	})
+	return r.param(p, name, "int64", func(s string) error {
+		tmp, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
 
-	return r
+		*i = tmp
+
+		return nil
+	})
 }
 
 // Aktiviere Body Daten überprüfung
@@ -138,7 +273,7 @@ func (r *request) ValidateBody() *request {
 
 // Aktiviere Base Auth
 func (r *request) BaseAuth(fn authFunc) *request {
-	r.authFunc = fn
+	r.authenticate = r.basicAuth(identityAuth(fn))
 
 	return r
 }
@@ -153,11 +288,21 @@ func (r *request) Put(model interface{}, p interface{}, n string, i *int64) *req
 	return r.DecodeBody(model).ValidateBody().ParamInt64(p, n, i)
 }
 
-// Decode JSON Body in übergebenes Object
+// Decode Body passend zum Content-Type in übergebenes Object
 func (r *request) decodeBody() Error {
-	err := json.Unmarshal(r.body, r.bodyObject)
+	contentType := r.request.Header.Get("Content-Type")
+	codec, ok := r.engine.codecRegistry().byContentType(contentType)
+	if !ok {
+		return unsupportedMediaType(contentType)
+	}
+
+	if r.strict && codec.Name() == JSONCodec.Name() {
+		return strictUnmarshal(r.body, r.bodyObject)
+	}
+
+	err := codec.Unmarshal(r.body, r.bodyObject)
 	if err != nil {
-		return NewError("Error cannot parse JSON body", err)
+		return NewError(fmt.Sprintf("Error cannot parse %v body", codec.Name()), err)
 	}
 
 	return nil
@@ -165,95 +310,94 @@ func (r *request) decodeBody() Error {
 }
 
 // Find Parameter in url
-func (r *request) queryParamInt64(v *paramInt64) Error {
-	switch v.data.(type) {
-	case httprouter.Params:
-		return queryHTTPRouterParams(v)
-	case gin.Params:
-		return queryGinParams(v)
-	default:
+func (r *request) queryParam(v *param) Error {
+	params, ok := paramSource(v.data)
+	if !ok {
 		err := errors.New("Not supported parameter store")
 		return NewError(err.Error(), err)
 	}
 
-	return nil
-}
-
-// Find Parameter in url httprouter
-func queryHTTPRouterParams(v *paramInt64) Error {
-	params := v.data.(httprouter.Params)
-	tmp := params.ByName(v.name)
-	i, err := strconv.ParseInt(tmp, 10, 64)
+	err := v.parse(params.ByName(v.name))
 	if err != nil {
-		msg := fmt.Sprintf("Error cannot find int64 parameter %v", v.name)
+		msg := fmt.Sprintf("Error cannot find %v parameter %v", v.kind, v.name)
 		return NewError(msg, err)
 	}
 
-	p := v.i64
-	*p = i
-
-	return nil
-}
-
-// Find Parameter in url
-func queryGinParams(v *paramInt64) Error {
-	params := v.data.(gin.Params)
-	tmp := params.ByName(v.name)
-	i, err := strconv.ParseInt(tmp, 10, 64)
-	if err != nil {
-		msg := fmt.Sprintf("Error cannot find int64 parameter %v", v.name)
-		return NewError(msg, err)
-	}
-
-	p := v.i64
-	*p = i
-
 	return nil
 }
 
 // Log request
 func (r *request) logRequest() {
-	r.logger.WithFields(logrus.Fields{
+	fields := logrus.Fields{
 		"remote_addr": r.request.RemoteAddr,
 		"method":      r.request.Method,
 		"url":         r.request.URL.String(),
 		"body":        fmt.Sprintf("%s", r.body),
-	}).Infoln()
+	}
+
+	if id := Principal(r.request); id != nil {
+		fields["principal"] = id.ID
+	}
+
+	r.logger.WithFields(fields).Infoln()
 }
 
 func (r *request) validateBody() Error {
-	config := &validator.Config{TagName: ValidatorTagName}
-	validate := validator.New(config)
-	errs := validate.Struct(r.bodyObject)
-	if errs != nil {
-		errMsgs := errs.(validator.ValidationErrors)
-		err := bytes.NewBufferString("")
-		for _, v := range errMsgs {
-			msg := fmt.Sprintf("%v failed due to %v", v.Field, v.Tag)
-			err.WriteString(msg)
-		}
+	return r.engine.validateStruct(r.bodyObject)
+}
 
-		return NewError(err.String(), fmt.Errorf("%v", err))
+// Auth User, der angemeldete Aufrufer wird im Context des Requests abgelegt
+func (r *request) auth() Error {
+	id, err := r.authenticate()
+	if err != nil {
+		return err
+	}
+
+	if id != anonymous {
+		r.setPrincipal(id)
 	}
 
 	return nil
 }
 
-// Auth User
-func (r *request) auth() Error {
-	user, password, _ := r.request.BasicAuth()
-	ok, err := r.authFunc(user, password)
-	if !ok || err != nil {
-		return NewError(fmt.Sprintf("Unauthorized user %v", user), err)
+func (r *request) basicAuth(fn func(string, string) (*Identity, error)) func() (*Identity, Error) {
+	return func() (*Identity, Error) {
+		user, password, _ := r.request.BasicAuth()
+		id, err := fn(user, password)
+		if id == nil || err != nil {
+			challenge := fmt.Sprintf("Basic realm=%q", r.engine.authRealm())
+			return nil, Unauthorized("Unauthorized user", err).
+				WithHeader("WWW-Authenticate", challenge)
+		}
+
+		return id, nil
 	}
+}
 
-	return nil
+// Aufrufer für Auth Funktionen die nur true oder false liefern
+func identityAuth(fn authFunc) func(string, string) (*Identity, error) {
+	return func(user, password string) (*Identity, error) {
+		ok, err := fn(user, password)
+		if !ok || err != nil {
+			return nil, err
+		}
+
+		if user == "" {
+			return anonymous, nil
+		}
+
+		return &Identity{ID: user}, nil
+	}
 }
 
 func (r *request) setBody() Error {
-	tmp, err := ioutil.ReadAll(r.request.Body)
+	if err := r.checkContentLength(); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.ReadAll(r.bodyReader())
 	if err != nil {
-		return NewError("Error while reading Process Body", err)
+		return r.bodyReadError(err)
 	}
 
 	r.body = tmp
@@ -271,8 +415,10 @@ func newLogger(old *logrus.Logger) *logrus.Logger {
 	return logger
 }
 
-func allIn(string, string) (bool, error) {
-	return true, nil
+// Ohne konfigurierte Anmeldung ist jeder Request anonym, ein mitgesendeter
+// Authorization Header wird ignoriert
+func anonymousAuth() (*Identity, Error) {
+	return anonymous, nil
 }
 
 // Erzeuge neuen Fehler
@@ -290,5 +436,9 @@ func (err requestError) Message() string {
 }
 
 func (err requestError) Error() string {
+	if err.err == nil {
+		return err.message
+	}
+
 	return err.err.Error()
 }
@@ -16,50 +16,152 @@ import (
 
 type (
 	response struct {
+		engine     *Engine
 		response   http.ResponseWriter
 		request    *http.Request
 		statusCode int
 		logger     *logrus.Logger
 		logID      string
 		body       []byte
+		problem    bool
+
+		idempotency *idempotencyEntry
+		recorder    *recordingWriter
+
+		autoETag     bool
+		weakETag     bool
+		version      VersionFunc
+		lastModified time.Time
 	}
 
 	errorResponse struct {
-		ID      string `json:"id"`
-		Message string `json:"message"`
+		ID      string       `json:"id"`
+		Message string       `json:"message"`
+		Code    string       `json:"code,omitempty"`
+		Errors  []FieldError `json:"errors,omitempty"`
 	}
+
+	// Fehler nach RFC 7807 (application/problem+json)
+	problemResponse map[string]interface{}
+)
+
+// Konfiguration der Problem Details (RFC 7807)
+var (
+	// Sende alle Fehler als application/problem+json
+	ProblemDetails = false
+	// Präfix für das type Feld, wird um den Code des Fehlers ergänzt.
+	// Ist das Präfix leer oder hat der Fehler keinen Code wird about:blank verwendet.
+	ProblemTypeBase = ""
 )
 
 func Response(w http.ResponseWriter, r *http.Request) *response {
-	logID, err := newLogID()
+	return defaultEngine().Response(w, r)
+}
+
+func newResponse(e *Engine, w http.ResponseWriter, r *http.Request) *response {
+	logID, err := e.logID()
 
 	resp := &response{
+		engine:     e,
 		response:   w,
 		request:    r,
 		statusCode: http.StatusOK,
-		logger:     Logger,
+		logger:     e.logger(),
 		logID:      logID,
 		body:       []byte("empty body"),
+		problem:    e.ProblemDetails,
 	}
 
 	if err != nil {
 		resp.logError(err)
 	}
 
+	// Antwort aufzeichnen um sie bei wiederholten Requests erneut zu senden
+	if entry := idempotencyFromContext(r.Context()); entry != nil {
+		resp.idempotency = entry
+		resp.recorder = &recordingWriter{ResponseWriter: w}
+		resp.response = resp.recorder
+	}
+
 	return resp
 }
 
+// Sende Fehler an den Client. Erfüllt err StatusError wird dessen HTTP Status
+// verwendet, ansonsten 400 Bad Request.
 func (r *response) Error(err Error) {
-	r.response.WriteHeader(http.StatusBadRequest)
+	if replay, ok := err.(idempotencyReplay); ok {
+		replay.write(r.response)
+		return
+	}
+	defer r.finish()
+
+	status, code, header := errorStatus(err)
+	for k, v := range header {
+		r.response.Header()[k] = v
+	}
+
+	format := r.engine.FormatError
+	if r.problem && format == nil {
+		r.response.Header().Set("Content-Type", "application/problem+json")
+	}
+
+	r.response.WriteHeader(status)
 
 	r.logError(err)
 
+	if format != nil {
+		r.json(format(r.request, err, status, r.logID))
+		return
+	}
+
+	if r.problem {
+		r.json(r.problemDetails(err, status, code))
+		return
+	}
+
 	r.json(errorResponse{
 		ID:      r.logID,
 		Message: err.Message(),
+		Code:    code,
+		Errors:  fieldErrors(err),
 	})
 }
 
+// Sende Fehler dieser Response als application/problem+json
+func (r *response) Problem() *response {
+	r.problem = true
+	return r
+}
+
+func (r *response) problemDetails(err Error, status int, code string) problemResponse {
+	p := problemResponse{}
+	if e, ok := err.(ExtensionError); ok {
+		for k, v := range e.Extensions() {
+			p[k] = v
+		}
+	}
+
+	typ := "about:blank"
+	if r.engine.ProblemTypeBase != "" && code != "" {
+		typ = r.engine.ProblemTypeBase + code
+	}
+
+	p["type"] = typ
+	p["title"] = http.StatusText(status)
+	p["status"] = status
+	p["detail"] = err.Message()
+	p["instance"] = r.request.URL.RequestURI()
+	p["id"] = r.logID
+	if code != "" {
+		p["code"] = code
+	}
+	if fields := fieldErrors(err); len(fields) > 0 {
+		p["errors"] = fields
+	}
+
+	return p
+}
+
 func (r *response) json(data interface{}) {
 	body, err := json.Marshal(data)
 	if err != nil {
@@ -83,6 +185,10 @@ func (r *response) logError(err error) {
 		"method":      r.request.Method,
 	})
 
+	if id := Principal(r.request); id != nil {
+		l = l.WithField("principal", id.ID)
+	}
+
 	body, e := ioutil.ReadAll(r.request.Body)
 	if e != nil {
 		if e == io.EOF {
@@ -100,15 +206,67 @@ func (r *response) logError(err error) {
 	l.WithFields(logrus.Fields{"body": string(body)}).Error(err)
 }
 
+// Sende die Daten von fn kodiert passend zum Accept Header.
+// Ist kein passender Codec registriert wird 406 Not Acceptable gesendet.
 func (r *response) Data(fn func() (interface{}, Error)) {
+	accept := r.request.Header.Get("Accept")
+	mediaType, codec, ok := r.engine.codecRegistry().negotiate(accept)
+	if !ok {
+		r.Error(notAcceptable(accept))
+		return
+	}
+
+	var etag string
+	if r.version != nil {
+		v, modified, err := r.version()
+		if err != nil {
+			r.Error(err)
+			return
+		}
+
+		etag = quoteETag(v)
+		if !modified.IsZero() {
+			r.lastModified = modified
+		}
+
+		// Ohne fn aufzurufen, die Header werden erst ohne Fehler gesetzt
+		if r.notModified(etag, r.lastModified) {
+			r.validators(etag, r.lastModified)
+			r.sendNotModified()
+			return
+		}
+	}
+
 	data, err := fn()
 	if err != nil {
 		r.Error(err)
 		return
 	}
 
+	body, e := codec.Marshal(data)
+	if e != nil {
+		r.Error(Internal("Error cannot encode response", e))
+		return
+	}
+
+	if r.version == nil && r.autoETag {
+		etag = NewETag(body, r.weakETag)
+	}
+
+	if r.validators(etag, r.lastModified) {
+		r.sendNotModified()
+		return
+	}
+
+	r.response.Header().Set("Content-Type", mediaType)
 	r.response.WriteHeader(r.statusCode)
-	r.json(data)
+
+	_, e = r.response.Write(body)
+	if e != nil {
+		r.logError(e)
+	}
+
+	r.finish()
 }
 
 func (r *response) StatusCode(c int) *response {
@@ -118,12 +276,24 @@ func (r *response) StatusCode(c int) *response {
 
 func (r *response) OK() {
 	r.response.WriteHeader(http.StatusOK)
+	r.finish()
 }
 
 func (r *response) Post(fn func() (interface{}, Error)) {
 	r.StatusCode(http.StatusCreated).Data(fn)
 }
 
+// Speichere die Antwort für Idempotent
+func (r *response) finish() {
+	if r.idempotency == nil {
+		return
+	}
+
+	if err := r.idempotency.finish(r.recorder); err != nil {
+		r.logError(err)
+	}
+}
+
 func newLogID() (string, error) {
 	total := 500
 	hash := sha1.New()
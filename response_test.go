@@ -2,6 +2,7 @@ package hrr
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -252,3 +253,104 @@ func Test_NewLogID(t *testing.T) {
 		i[k] = true
 	}
 }
+
+func Test_ResponseProblemDetails(t *testing.T) {
+	tc := struct {
+		Err                 Error
+		URL                 string
+		ExpectedStatus      int
+		ExpectedContentType string
+		ExpectedBody        map[string]interface{}
+	}{
+		Err:                 NotFound("Monster not found", errors.New("no rows")).WithExtension("monster_id", 7),
+		URL:                 "/v0/monster/7?x=1",
+		ExpectedStatus:      http.StatusNotFound,
+		ExpectedContentType: "application/problem+json",
+		ExpectedBody: map[string]interface{}{
+			"type":       "https://example.com/problems/not_found",
+			"title":      "Not Found",
+			"status":     float64(http.StatusNotFound),
+			"detail":     "Monster not found",
+			"instance":   "/v0/monster/7?x=1",
+			"code":       "not_found",
+			"monster_id": float64(7),
+		},
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+		ProblemTypeBase = "https://example.com/problems/"
+		defer func() { ProblemTypeBase = "" }()
+
+		req := NewRequest(t, "GET", tc.URL, &bytes.Buffer{})
+		resp := httptest.NewRecorder()
+		Response(resp, req).Problem().Error(tc.Err)
+
+		if resp.Code != tc.ExpectedStatus {
+			t.Fatalf("Expected %v was %v", tc.ExpectedStatus, resp.Code)
+		}
+
+		if ct := resp.Header().Get("Content-Type"); ct != tc.ExpectedContentType {
+			t.Fatalf("Expected %v was %v", tc.ExpectedContentType, ct)
+		}
+
+		body := map[string]interface{}{}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+
+		for k, v := range tc.ExpectedBody {
+			if body[k] != v {
+				t.Fatalf("Expected %v = %v was %v", k, v, body[k])
+			}
+		}
+
+		if id, ok := body["id"].(string); !ok || id == "" {
+			t.Fatalf("Expected log id was %v", body["id"])
+		}
+	}
+}
+
+func Test_ResponseProblemDetailsGlobal(t *testing.T) {
+	tc := struct {
+		Body         string
+		ExpectedBody string
+	}{
+		Body: `{"value": ""}`,
+		ExpectedBody: `
+		{
+			"code": "validation_failed",
+			"detail": "Value failed due to required",
+			"errors": \[\{"field": "value", "tag": "required", "value": ""\}\],
+			"id": "\w*",
+			"instance": "/",
+			"status": 400,
+			"title": "Bad Request",
+			"type": "about:blank"
+		}
+		`,
+	}
+
+	// Run test
+	{
+		logger, _ := test.NewNullLogger()
+		Logger = logger
+		ProblemDetails = true
+		defer func() { ProblemDetails = false }()
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+		resp := httptest.NewRecorder()
+
+		var body TestObject
+		err := Request(req).Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		Response(resp, req).Error(err)
+
+		EqualJSONBody(t, tc.ExpectedBody, resp.Body)
+	}
+}
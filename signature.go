@@ -0,0 +1,231 @@
+package hrr
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Zerlegt den Signatur Header in Zeitstempel und Signaturen.
+	// Ist der Zeitstempel nicht leer wird "zeitstempel.body" signiert, sonst nur der Body.
+	SignatureFormat func(header string) (timestamp string, signatures []string, err error)
+
+	// Konfiguration für VerifySignature
+	SignatureConfig struct {
+		// Header mit der Signatur, z.B. X-Hub-Signature-256 oder Stripe-Signature
+		Header string
+		Format SignatureFormat
+		// Mehrere Secrets erlauben das Wechseln eines Secrets ohne Ausfall
+		Secrets [][]byte
+		// Hash Funktion für HMAC, Standard ist SHA-256
+		Hash func() hash.Hash
+		// Maximale Abweichung des Zeitstempels, 0 bedeutet keine Prüfung
+		Tolerance time.Duration
+
+		// Optionaler Speicher für bereits gesehene Nonces
+		Nonces NonceStore
+		// Header mit der Nonce, ohne Angabe wird die Signatur verwendet
+		NonceHeader string
+		// Wie lange eine Nonce gespeichert wird, Standard ist Tolerance oder 24 Stunden
+		NonceTTL time.Duration
+	}
+
+	// Speicher für Nonces um wiederholte Requests zu erkennen
+	NonceStore interface {
+		// Speichert nonce bis expires, liefert true wenn sie bereits gespeichert war
+		Seen(nonce string, expires time.Time) (bool, error)
+	}
+
+	// Nonces im Speicher, abgelaufene Nonces werden beim Speichern entfernt
+	MemoryNonceStore struct {
+		mu     sync.Mutex
+		nonces map[string]time.Time
+	}
+)
+
+var (
+	errMissingSignature = errors.New("Missing signature")
+	errInvalidSignature = errors.New("Invalid signature")
+	errSignatureExpired = errors.New("Signature timestamp outside tolerance")
+	errReplayedRequest  = errors.New("Replayed request")
+	errMissingNonce     = errors.New("Missing nonce")
+)
+
+// Signatur im Format sha256=<hex> wie bei GitHub Webhooks
+func GitHubSignature(header string) (string, []string, error) {
+	i := strings.Index(header, "=")
+	if i < 0 {
+		return "", []string{header}, nil
+	}
+
+	return "", []string{header[i+1:]}, nil
+}
+
+// Signatur im Format t=<unix>,v1=<hex>,v1=<hex> wie bei Stripe Webhooks
+func StripeSignature(header string) (string, []string, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+
+	if timestamp == "" {
+		return "", nil, errMissingSignature
+	}
+
+	return timestamp, signatures, nil
+}
+
+// Prüfe die HMAC Signatur des Bodys. Der Body wird dafür immer vollständig gelesen,
+// auch wenn StreamBody aktiviert ist.
+func (r *request) VerifySignature(config SignatureConfig) *request {
+	r.signature = &config
+	return r
+}
+
+func (r *request) verifySignature() Error {
+	config := r.signature
+
+	header := r.request.Header.Get(config.Header)
+	if header == "" {
+		return Unauthorized("Error missing signature", errMissingSignature)
+	}
+
+	format := config.Format
+	if format == nil {
+		format = GitHubSignature
+	}
+
+	timestamp, signatures, err := format(header)
+	if err != nil || len(signatures) == 0 {
+		return Unauthorized("Error invalid signature", errInvalidSignature)
+	}
+
+	var sent time.Time
+	if timestamp != "" && config.Tolerance > 0 {
+		sec, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return Unauthorized("Error invalid signature", err)
+		}
+
+		sent = time.Unix(sec, 0)
+		d := time.Since(sent)
+		if d > config.Tolerance || d < -config.Tolerance {
+			return Unauthorized("Error signature expired", errSignatureExpired)
+		}
+	}
+
+	signature, ok := matchSignature(config, timestamp, r.body, signatures)
+	if !ok {
+		return Unauthorized("Error invalid signature", errInvalidSignature)
+	}
+
+	if config.Nonces == nil {
+		return nil
+	}
+
+	nonce := signature
+	if config.NonceHeader != "" {
+		nonce = r.request.Header.Get(config.NonceHeader)
+		if nonce == "" {
+			return Unauthorized("Error missing nonce", errMissingNonce)
+		}
+	}
+
+	ttl := config.NonceTTL
+	if ttl == 0 {
+		ttl = config.Tolerance
+	}
+	if ttl == 0 {
+		ttl = 24 * time.Hour
+	}
+
+	// Die Nonce muss gespeichert bleiben solange der Zeitstempel akzeptiert wird,
+	// auch wenn er bis zu Tolerance in der Zukunft liegt
+	expires := time.Now().Add(ttl)
+	if !sent.IsZero() && sent.Add(config.Tolerance).After(expires) {
+		expires = sent.Add(config.Tolerance)
+	}
+
+	seen, err := config.Nonces.Seen(nonce, expires)
+	if err != nil {
+		return Internal("Error cannot check nonce", err)
+	}
+
+	if seen {
+		return Unauthorized("Error replayed request", errReplayedRequest)
+	}
+
+	return nil
+}
+
+// Liefert die erste gültige Signatur
+func matchSignature(config *SignatureConfig, timestamp string, body []byte, signatures []string) (string, bool) {
+	newHash := config.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+
+	for _, secret := range config.Secrets {
+		mac := hmac.New(newHash, secret)
+		if timestamp != "" {
+			mac.Write([]byte(timestamp + "."))
+		}
+		mac.Write(body)
+		expected := mac.Sum(nil)
+
+		for _, s := range signatures {
+			actual, err := hex.DecodeString(s)
+			if err != nil {
+				continue
+			}
+
+			if hmac.Equal(expected, actual) {
+				return s, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}}
+}
+
+func (s *MemoryNonceStore) Seen(nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, v := range s.nonces {
+		if now.After(v) {
+			delete(s.nonces, k)
+		}
+	}
+
+	if _, ok := s.nonces[nonce]; ok {
+		return true, nil
+	}
+
+	s.nonces[nonce] = expires
+
+	return false, nil
+}
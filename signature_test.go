@@ -0,0 +1,177 @@
+package hrr
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Test_VerifySignature_GitHub(t *testing.T) {
+	body := `{"action":"opened"}`
+	config := SignatureConfig{
+		Header:  "X-Hub-Signature-256",
+		Format:  GitHubSignature,
+		Secrets: [][]byte{[]byte("old"), []byte("secret")},
+	}
+
+	tc := []struct {
+		Signature      string
+		ExpectedStatus int
+	}{
+		{Signature: "sha256=" + sign("secret", body)},
+		{Signature: "sha256=" + sign("old", body)},
+		{Signature: "sha256=" + sign("wrong", body), ExpectedStatus: http.StatusUnauthorized},
+		{Signature: "sha256=nothex", ExpectedStatus: http.StatusUnauthorized},
+		{ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	// Run test
+	for i, v := range tc {
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(body))
+		if v.Signature != "" {
+			req.Header.Set("X-Hub-Signature-256", v.Signature)
+		}
+
+		var event struct {
+			Action string `json:"action"`
+		}
+		err := Request(req).DecodeBody(&event).StreamBody().VerifySignature(config).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+
+			if event.Action != "opened" {
+				t.Fatalf("%v: Expected opened was %v", i, event.Action)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+}
+
+func Test_VerifySignature_Stripe(t *testing.T) {
+	body := `{"type":"charge.succeeded"}`
+	config := SignatureConfig{
+		Header:    "Stripe-Signature",
+		Format:    StripeSignature,
+		Secrets:   [][]byte{[]byte("secret")},
+		Tolerance: 5 * time.Minute,
+		Nonces:    NewMemoryNonceStore(),
+	}
+
+	now := time.Now().Unix()
+	old := time.Now().Add(-time.Hour).Unix()
+	valid := fmt.Sprintf("t=%v,v1=%v", now, sign("secret", fmt.Sprintf("%v.%v", now, body)))
+
+	tc := []struct {
+		Body           string
+		Signature      string
+		ExpectedStatus int
+	}{
+		{Signature: valid},
+		// Wiederholter Request
+		{Signature: valid, ExpectedStatus: http.StatusUnauthorized},
+		{Body: body + " ", Signature: fmt.Sprintf("t=%v,v1=%v,v1=%v", now, sign("x", "y"), sign("secret", fmt.Sprintf("%v.%v ", now, body)))},
+		{Signature: fmt.Sprintf("t=%v,v1=%v", old, sign("secret", fmt.Sprintf("%v.%v", old, body))), ExpectedStatus: http.StatusUnauthorized},
+		{Signature: fmt.Sprintf("t=%v,v1=%v", now, sign("secret", body)), ExpectedStatus: http.StatusUnauthorized},
+		{Signature: "v1=" + sign("secret", body), ExpectedStatus: http.StatusUnauthorized},
+	}
+
+	// Run test
+	for i, v := range tc {
+		payload := body
+		if v.Body != "" {
+			payload = v.Body
+		}
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(payload))
+		req.Header.Set("Stripe-Signature", v.Signature)
+
+		err := Request(req).VerifySignature(config).Process()
+		if v.ExpectedStatus == 0 {
+			if err != nil {
+				t.Fatalf("%v: %v", i, err)
+			}
+			continue
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != v.ExpectedStatus {
+			t.Fatalf("%v: Expected %v was %v", i, v.ExpectedStatus, err)
+		}
+	}
+}
+
+type recordingNonceStore struct {
+	expires time.Time
+}
+
+func (s *recordingNonceStore) Seen(nonce string, expires time.Time) (bool, error) {
+	s.expires = expires
+	return false, nil
+}
+
+func Test_VerifySignatureFutureTimestamp(t *testing.T) {
+	body := `{"type":"charge.succeeded"}`
+	store := &recordingNonceStore{}
+	config := SignatureConfig{
+		Header:    "Stripe-Signature",
+		Format:    StripeSignature,
+		Secrets:   [][]byte{[]byte("secret")},
+		Tolerance: 5 * time.Minute,
+		Nonces:    store,
+	}
+
+	sent := time.Now().Add(config.Tolerance - time.Second).Unix()
+	signature := fmt.Sprintf("t=%v,v1=%v", sent, sign("secret", fmt.Sprintf("%v.%v", sent, body)))
+
+	// Run test
+	{
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(body))
+		req.Header.Set("Stripe-Signature", signature)
+
+		if err := Request(req).VerifySignature(config).Process(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Solange der Zeitstempel gültig ist muss die Nonce bekannt bleiben
+		expected := time.Unix(sent, 0).Add(config.Tolerance)
+		if store.expires.Before(expected) {
+			t.Fatalf("Expected nonce until %v was %v", expected, store.expires)
+		}
+	}
+}
+
+func Test_MemoryNonceStore(t *testing.T) {
+	store := NewMemoryNonceStore()
+
+	// Run test
+	{
+		if seen, _ := store.Seen("a", time.Now().Add(-time.Second)); seen {
+			t.Fatal("Expected a to be new")
+		}
+
+		// Abgelaufene Nonce wird entfernt
+		if seen, _ := store.Seen("a", time.Now().Add(time.Minute)); seen {
+			t.Fatal("Expected expired a to be new")
+		}
+
+		if seen, _ := store.Seen("a", time.Now().Add(time.Minute)); !seen {
+			t.Fatal("Expected a to be seen")
+		}
+	}
+}
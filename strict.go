@@ -0,0 +1,254 @@
+package hrr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+	errUnknownField = errors.New("Unknown field")
+	errDuplicateKey = errors.New("Duplicate key")
+	errTrailingData = errors.New("Trailing data")
+)
+
+// Dekodiere JSON Bodys streng: unbekannte Felder, doppelte Schlüssel und Daten nach
+// dem JSON Wert werden abgelehnt. Gilt nur für JSON Bodys, bei StreamBody werden
+// doppelte Schlüssel nicht erkannt.
+func (r *request) Strict() *request {
+	r.strict = true
+	return r
+}
+
+// Prüft data gegen den Typ von v und dekodiert anschließend
+func strictUnmarshal(data []byte, v interface{}) Error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := walkStrictJSON(dec, reflect.TypeOf(v), "$"); err != nil {
+		return err
+	}
+
+	offset := dec.InputOffset()
+	if _, err := dec.Token(); err != io.EOF {
+		trimmed := bytes.TrimLeft(data[offset:], " \t\r\n")
+		return strictError(errTrailingData, "$", offset+int64(len(data[offset:])-len(trimmed)))
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return jsonDecodeError(err)
+	}
+
+	return nil
+}
+
+// Dekodiert direkt aus dem Reader, unbekannte Felder und Daten nach dem
+// JSON Wert werden abgelehnt
+func strictDecode(r io.Reader, v interface{}) Error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		// encoding/json meldet unbekannte Felder nur mit Namen
+		if name := strings.TrimPrefix(err.Error(), "json: unknown field "); name != err.Error() {
+			return strictError(errUnknownField, "$."+strings.Trim(name, `"`), dec.InputOffset())
+		}
+
+		return jsonDecodeError(err)
+	}
+
+	offset := dec.InputOffset()
+	if _, err := dec.Token(); err != io.EOF {
+		return strictError(errTrailingData, "$", offset)
+	}
+
+	return nil
+}
+
+// Läuft über alle Tokens eines JSON Werts und vergleicht die Schlüssel mit dem Typ t.
+// Ist t nil wird der Wert nur auf doppelte Schlüssel geprüft.
+func walkStrictJSON(dec *json.Decoder, t reflect.Type, path string) Error {
+	tok, err := dec.Token()
+	if err != nil {
+		return jsonDecodeError(err)
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	t = strictType(t)
+
+	switch delim {
+	case '{':
+		keys := map[string]bool{}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return jsonDecodeError(err)
+			}
+
+			key := tok.(string)
+			keyPath := path + "." + key
+			if keys[key] {
+				return strictError(errDuplicateKey, keyPath, dec.InputOffset())
+			}
+			keys[key] = true
+
+			child, known := strictFieldType(t, key)
+			if !known {
+				return strictError(errUnknownField, keyPath, dec.InputOffset())
+			}
+
+			if err := walkStrictJSON(dec, child, keyPath); err != nil {
+				return err
+			}
+		}
+	case '[':
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+
+		for i := 0; dec.More(); i++ {
+			if err := walkStrictJSON(dec, elem, fmt.Sprintf("%v[%v]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Schließende Klammer
+	if _, err := dec.Token(); err != nil {
+		return jsonDecodeError(err)
+	}
+
+	return nil
+}
+
+// Entfernt Pointer, Typen mit eigener Dekodierung werden nicht geprüft
+func strictType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		if t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType) {
+			return nil
+		}
+		t = t.Elem()
+	}
+
+	if t == nil {
+		return nil
+	}
+
+	p := reflect.PtrTo(t)
+	if p.Implements(jsonUnmarshalerType) || p.Implements(textUnmarshalerType) {
+		return nil
+	}
+
+	return t
+}
+
+// Typ des Feldes key, false wenn t ein Struct ohne passendes Feld ist
+func strictFieldType(t reflect.Type, key string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, true
+	}
+
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem(), true
+	case reflect.Struct:
+		if f, ok := jsonField(t, key); ok {
+			return f.Type, true
+		}
+
+		return nil, false
+	}
+
+	// Falscher Typ, wird von json.Unmarshal gemeldet
+	return nil, true
+}
+
+// Sucht ein Feld wie encoding/json, exakte Namen haben Vorrang.
+// Index enthält bei eingebetteten Structs den vollständigen Pfad.
+func jsonField(t reflect.Type, key string) (reflect.StructField, bool) {
+	var fold *reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		name := strings.SplitN(tag, ",", 2)[0]
+		if name == "-" && !strings.Contains(tag, ",") {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				if sf, ok := jsonField(ft, key); ok {
+					sf.Index = append([]int{i}, sf.Index...)
+					return sf, true
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		if name == key {
+			return f, true
+		}
+
+		if fold == nil && strings.EqualFold(name, key) {
+			tmp := f
+			fold = &tmp
+		}
+	}
+
+	if fold != nil {
+		return *fold, true
+	}
+
+	return reflect.StructField{}, false
+}
+
+func strictError(err error, path string, offset int64) requestError {
+	msg := fmt.Sprintf("Error %v %v at offset %v", strings.ToLower(err.Error()), path, offset)
+	return NewError(msg, err).
+		WithExtension("path", path).
+		WithExtension("offset", offset)
+}
+
+func jsonDecodeError(err error) requestError {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return NewError("Error cannot parse JSON body", err).
+			WithExtension("offset", syntaxErr.Offset)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		path := "$"
+		if typeErr.Field != "" {
+			path += "." + typeErr.Field
+		}
+
+		msg := fmt.Sprintf("Error invalid value for %v at offset %v", path, typeErr.Offset)
+		return NewError(msg, err).
+			WithExtension("path", path).
+			WithExtension("offset", typeErr.Offset)
+	}
+
+	return NewError("Error cannot parse JSON body", err)
+}
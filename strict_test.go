@@ -0,0 +1,110 @@
+package hrr
+
+import (
+	"bytes"
+	"testing"
+)
+
+type strictMonster struct {
+	Name     string `json:"name"`
+	Cuteness int    `json:"cuteness"`
+	Skills   []struct {
+		Name string `json:"name"`
+	} `json:"skills"`
+	Extra map[string]interface{} `json:"extra"`
+}
+
+func Test_StrictDecoding(t *testing.T) {
+	tc := []struct {
+		Body           string
+		ExpectedMsg    string
+		ExpectedPath   interface{}
+		ExpectedOffset interface{}
+	}{
+		{
+			Body:        `{"name": "Fluffy", "cuteness": 1, "skills": [{"name": "bite"}], "extra": {"any": {"x": 1}}}`,
+			ExpectedMsg: "",
+		},
+		{
+			Body:           `{"name": "Fluffy", "cutness": 1}`,
+			ExpectedMsg:    "Error unknown field $.cutness at offset 28",
+			ExpectedPath:   "$.cutness",
+			ExpectedOffset: int64(28),
+		},
+		{
+			Body:           `{"skills": [{"name": "bite"}, {"nam": "claw"}]}`,
+			ExpectedMsg:    "Error unknown field $.skills[1].nam at offset 36",
+			ExpectedPath:   "$.skills[1].nam",
+			ExpectedOffset: int64(36),
+		},
+		{
+			Body:           `{"name": "Fluffy", "name": "Flauschi"}`,
+			ExpectedMsg:    "Error duplicate key $.name at offset 25",
+			ExpectedPath:   "$.name",
+			ExpectedOffset: int64(25),
+		},
+		{
+			Body:           `{"name": "Fluffy"} {"name": "Flauschi"}`,
+			ExpectedMsg:    "Error trailing data $ at offset 19",
+			ExpectedPath:   "$",
+			ExpectedOffset: int64(19),
+		},
+		{
+			Body:           `{"cuteness": "very"}`,
+			ExpectedMsg:    "Error invalid value for $.cuteness at offset 19",
+			ExpectedPath:   "$.cuteness",
+			ExpectedOffset: int64(19),
+		},
+	}
+
+	// Run test
+	for _, v := range tc {
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(v.Body))
+
+		var body strictMonster
+		err := Request(req).DecodeBody(&body).Strict().Process()
+		if v.ExpectedMsg == "" {
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		if err == nil || err.Message() != v.ExpectedMsg {
+			t.Fatalf("Expected %v was %v", v.ExpectedMsg, err)
+		}
+
+		ext := err.(ExtensionError).Extensions()
+		if ext["path"] != v.ExpectedPath || ext["offset"] != v.ExpectedOffset {
+			t.Fatalf("Expected (%v, %v) was %v", v.ExpectedPath, v.ExpectedOffset, ext)
+		}
+	}
+}
+
+func Test_StrictDecodingStream(t *testing.T) {
+	tc := struct {
+		Body         string
+		ExpectedPath string
+	}{
+		Body:         `{"name": "Fluffy", "cutness": 1}`,
+		ExpectedPath: "$.cutness",
+	}
+
+	// Run test
+	{
+		StrictDecoding = true
+		defer func() { StrictDecoding = false }()
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+
+		var body strictMonster
+		err := Request(req).DecodeBody(&body).StreamBody().Process()
+		if err == nil {
+			t.Fatal("Expected error")
+		}
+
+		if p := err.(ExtensionError).Extensions()["path"]; p != tc.ExpectedPath {
+			t.Fatalf("Expected %v was %v", tc.ExpectedPath, p)
+		}
+	}
+}
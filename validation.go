@@ -0,0 +1,210 @@
+package hrr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type (
+	// Ein Feld das bei der Validierung durchgefallen ist.
+	// Field ist der Name des Feldes wie ihn der Client sendet, z.B. der JSON Name.
+	FieldError struct {
+		Field string      `json:"field"`
+		Tag   string      `json:"tag"`
+		Param string      `json:"param,omitempty"`
+		Value interface{} `json:"value"`
+	}
+
+	// Optionale Erweiterung von Error um die Liste aller fehlerhaften Felder
+	ValidationError interface {
+		Error
+		FieldErrors() []FieldError
+	}
+)
+
+var (
+	validatorOnce   sync.Once
+	sharedValidator *validator.Validate
+
+	// Tags aus denen der Name eines Feldes für Fehlermeldungen gelesen wird
+	fieldNameTags = []string{"json", "query", "header", "cookie"}
+)
+
+// Liefert den Validator der von Request verwendet wird.
+// Eigene Regeln, Struct Validierungen und Aliase sollten beim Start registriert werden,
+// ValidatorTagName muss vor dem ersten Aufruf gesetzt sein.
+func Validator() *validator.Validate {
+	validatorOnce.Do(func() {
+		sharedValidator = newValidator(ValidatorTagName)
+	})
+
+	return sharedValidator
+}
+
+func newValidator(tagName string) *validator.Validate {
+	v := validator.New()
+	v.SetTagName(tagName)
+	v.RegisterTagNameFunc(fieldName)
+
+	return v
+}
+
+// Prüft ob alle Validierungs Tags der übergebenen Models bekannt sind.
+// Sollte nach dem Registrieren eigener Regeln aufgerufen werden, z.B. Tippfehler
+// wie `validate:"requried"` fallen so bereits beim Start auf.
+func CheckModels(models ...interface{}) error {
+	return checkModels(Validator(), models...)
+}
+
+func checkModels(v *validator.Validate, models ...interface{}) error {
+	for _, m := range models {
+		t := reflect.TypeOf(m)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			return fmt.Errorf("Expected struct was %T", m)
+		}
+
+		obj := reflect.New(t).Interface()
+		if err := safeValidate(v, obj); err != nil {
+			if _, ok := err.(validator.ValidationErrors); !ok {
+				return fmt.Errorf("%v: %v", t, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (err requestError) FieldErrors() []FieldError {
+	return err.fields
+}
+
+// Validiere obj mit dem Validator der Engine. Bei Slices und Arrays wird jedes
+// Element einzeln validiert, der Index steht vor dem Feld, z.B. [3].name.
+func (e *Engine) validateStruct(obj interface{}) Error {
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		fields, msgs, err := e.structErrors(obj, "")
+		if err != nil || len(fields) == 0 {
+			return err
+		}
+
+		return validationFailed(strings.Join(msgs, ", "), fields)
+	}
+
+	fields := []FieldError{}
+	msgs := []string{}
+	for i := 0; i < val.Len(); i++ {
+		elem := val.Index(i)
+		for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+
+		if elem.Kind() != reflect.Struct {
+			continue
+		}
+
+		f, m, err := e.structErrors(elem.Interface(), fmt.Sprintf("[%v].", i))
+		if err != nil {
+			return err
+		}
+
+		fields = append(fields, f...)
+		msgs = append(msgs, m...)
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return validationFailed(strings.Join(msgs, ", "), fields)
+}
+
+// Fehlerhafte Felder eines Structs, prefix wird jedem Feld vorangestellt
+func (e *Engine) structErrors(obj interface{}, prefix string) ([]FieldError, []string, Error) {
+	errs := safeValidate(e.Validator(), obj)
+	if errs == nil {
+		return nil, nil, nil
+	}
+
+	verrs, ok := errs.(validator.ValidationErrors)
+	if !ok {
+		return nil, nil, Internal("Error cannot validate", errs)
+	}
+
+	fields := make([]FieldError, 0, len(verrs))
+	msgs := make([]string, 0, len(verrs))
+	for _, v := range verrs {
+		fields = append(fields, FieldError{
+			Field: prefix + fieldPath(v.Namespace()),
+			Tag:   v.Tag(),
+			Param: v.Param(),
+			Value: v.Value(),
+		})
+		msgs = append(msgs, fmt.Sprintf("%v%v failed due to %v", prefix, v.StructField(), v.Tag()))
+	}
+
+	return fields, msgs, nil
+}
+
+// Fehler mit Code validation_failed und der Liste aller fehlerhaften Felder
+func validationFailed(msg string, fields []FieldError) requestError {
+	err := NewStatusError(http.StatusBadRequest, "validation_failed", msg, errors.New(msg))
+	err.fields = fields
+
+	return err
+}
+
+// Der Validator bricht bei unbekannten Tags mit panic ab
+func safeValidate(v *validator.Validate, obj interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	return v.Struct(obj)
+}
+
+// Name eines Feldes wie ihn der Client sieht
+func fieldName(f reflect.StructField) string {
+	for _, tag := range fieldNameTags {
+		name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+
+	return f.Name
+}
+
+// Entfernt den Namen des äußeren Structs aus dem Pfad eines Feldes
+func fieldPath(namespace string) string {
+	i := strings.Index(namespace, ".")
+	if i < 0 {
+		return namespace
+	}
+
+	return namespace[i+1:]
+}
+
+func fieldErrors(err Error) []FieldError {
+	if e, ok := err.(ValidationError); ok {
+		return e.FieldErrors()
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package hrr
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Sirupsen/logrus/hooks/test"
+	"github.com/go-playground/validator/v10"
+)
+
+func Test_ValidateBodyFieldErrors(t *testing.T) {
+	tc := struct {
+		Body         string
+		ExpectedBody string
+	}{
+		Body: `
+		{
+			"name": "",
+			"cuteness": 200
+		}
+		`,
+		ExpectedBody: `
+		{
+			"id": "\w*",
+			"message": "Name failed due to required, Cuteness failed due to max",
+			"code": "validation_failed",
+			"errors": \[
+				\{"field": "name", "tag": "required", "value": ""\},
+				\{"field": "cuteness", "tag": "max", "param": "100", "value": 200\}
+			\]
+		}
+		`,
+	}
+
+	// Run test
+	{
+		tmp, _ := test.NewNullLogger()
+		Logger = tmp
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+
+		body := struct {
+			Name     string `json:"name" validate:"required"`
+			Cuteness int    `json:"cuteness,omitempty" validate:"max=100"`
+		}{}
+		err := Request(req).Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		resp := httptest.NewRecorder()
+		Response(resp, req).Error(err)
+
+		EqualJSONBody(t, tc.ExpectedBody, resp.Body)
+	}
+}
+
+func Test_ValidateSliceBody(t *testing.T) {
+	tc := struct {
+		Body         string
+		ExpectedBody string
+	}{
+		Body: `[{"value": "a"}, {"value": ""}, {"value": "c"}, {"value": ""}]`,
+		ExpectedBody: `
+		{
+			"id": "\w*",
+			"message": "\[1\].Value failed due to required, \[3\].Value failed due to required",
+			"code": "validation_failed",
+			"errors": \[
+				\{"field": "\[1\].value", "tag": "required", "value": ""\},
+				\{"field": "\[3\].value", "tag": "required", "value": ""\}
+			\]
+		}
+		`,
+	}
+
+	// Run test
+	{
+		tmp, _ := test.NewNullLogger()
+		Logger = tmp
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+
+		body := []*TestObject{}
+		err := Request(req).Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected validation error")
+		}
+
+		resp := httptest.NewRecorder()
+		Response(resp, req).Error(err)
+
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("Expected %v was %v", http.StatusBadRequest, resp.Code)
+		}
+
+		EqualJSONBody(t, tc.ExpectedBody, resp.Body)
+	}
+
+	// Gültige Elemente
+	{
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(`[{"value": "a"}]`))
+
+		body := []TestObject{}
+		if err := Request(req).Post(&body).Process(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_ValidatorCustomRules(t *testing.T) {
+	tc := struct {
+		Body     string
+		Expected string
+	}{
+		Body:     `{"name": "Fluffy"}`,
+		Expected: "Name failed due to monstername",
+	}
+
+	// Run test
+	{
+		err := Validator().RegisterValidation("monstername", func(fl validator.FieldLevel) bool {
+			return strings.HasPrefix(fl.Field().String(), "Monster")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		type namedMonster struct {
+			Name string `json:"name" validate:"monstername"`
+		}
+
+		if err := CheckModels(namedMonster{}); err != nil {
+			t.Fatal(err)
+		}
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(tc.Body))
+
+		var body namedMonster
+		e := Request(req).Post(&body).Process()
+		if e == nil || e.Message() != tc.Expected {
+			t.Fatalf("Expected %v was %v", tc.Expected, e)
+		}
+	}
+}
+
+func Test_CheckModelsUnknownTag(t *testing.T) {
+	type typoMonster struct {
+		Cuteness int `json:"cuteness" validate:"requried"`
+	}
+
+	// Run test
+	{
+		if err := CheckModels(Monster{}, &Skill{}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := CheckModels(typoMonster{}); err == nil {
+			t.Fatal("Expected error for unknown tag")
+		}
+
+		req := NewRequest(t, "POST", "/", bytes.NewBufferString(`{"cuteness": 1}`))
+
+		var body typoMonster
+		err := Request(req).Post(&body).Process()
+		if err == nil {
+			t.Fatal("Expected error for unknown tag")
+		}
+
+		if s, ok := err.(StatusError); !ok || s.Status() != http.StatusInternalServerError {
+			t.Fatalf("Expected %v was %v", http.StatusInternalServerError, err)
+		}
+	}
+}